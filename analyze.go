@@ -0,0 +1,145 @@
+package mode
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// LanguageShare is the share of a Mode in a directory tree, weighted by byte size
+type LanguageShare struct {
+	Mode    Mode
+	Bytes   int64
+	Percent float64
+}
+
+// Breakdown is the language breakdown of a directory tree, like "72% Go, 20% Shell, 8% Markdown"
+type Breakdown struct {
+	Primary   Mode            // the mode with the most bytes, or Blank if no files were counted
+	Languages []LanguageShare // sorted by size, largest first
+	Total     int64           // the total number of bytes that were counted
+}
+
+var (
+	// documentationDirectories are directory names that usually only contain documentation
+	documentationDirectories = []string{"Documentation", "doc", "docs", "examples"}
+
+	// documentationNames are uppercase filenames, without the extension, for files that are only documentation
+	documentationNames = []string{"AUTHORS", "CHANGELOG", "CHANGES", "CONTRIBUTING", "COPYING", "HISTORY", "LICENSE", "NEWS", "README"}
+
+	// documentationExtensions are the extensions that the documentationNames may have, like README.md
+	documentationExtensions = []string{"", ".adoc", ".markdown", ".md", ".org", ".rst", ".txt"}
+
+	// metadataFilenames are repository metadata files that are not counted, like linguist does
+	metadataFilenames = []string{".gitattributes", ".gitignore", ".gitkeep", ".gitmodules", ".mailmap"}
+)
+
+// isDocumentation checks if the given slash-separated path looks like documentation
+func isDocumentation(name string) bool {
+	dir, base := path.Split(name)
+	for _, segment := range strings.Split(strings.TrimSuffix(dir, "/"), "/") {
+		if hasS(documentationDirectories, segment) {
+			return true
+		}
+	}
+	ext := path.Ext(base)
+	return hasS(documentationNames, strings.ToUpper(strings.TrimSuffix(base, ext))) && hasS(documentationExtensions, strings.ToLower(ext))
+}
+
+// override returns the given default value, unless the given attribute state is set or unset
+func override(state AttributeState, defaultValue bool) bool {
+	switch state {
+	case AttributeSet:
+		return true
+	case AttributeUnset:
+		return false
+	}
+	return defaultValue
+}
+
+// Analyze walks the given directory in the given file system, detects the mode of every file
+// and returns the share of each mode, weighted by byte size.
+// Vendored, generated, documentation and binary files are excluded, as well as files
+// that are ignored by .gitignore files, metadata files like .gitignore and .gitattributes
+// and files where no mode could be detected.
+// Linguist overrides in .gitattributes files take precedence over the built-in rules.
+func Analyze(fsys fs.FS, root string) (Breakdown, error) {
+	var (
+		b          Breakdown
+		ignore     IgnoreRules
+		attributes GitAttributes
+		perMode    = make(map[Mode]int64)
+	)
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		if root == "." {
+			rel = p
+		}
+		if d.IsDir() {
+			if rel != "" && (d.Name() == ".git" || ignore.Ignored(rel, true)) {
+				return fs.SkipDir
+			}
+			// Skip vendor directories, unless .gitattributes may say otherwise for some of the files
+			if rel != "" && IsVendored(rel) && !attributes.mentions("linguist-vendored", AttributeUnset) {
+				return fs.SkipDir
+			}
+			if data, err := fs.ReadFile(fsys, path.Join(p, ".gitignore")); err == nil {
+				ignore.Add(data, rel)
+			}
+			if data, err := fs.ReadFile(fsys, path.Join(p, ".gitattributes")); err == nil {
+				attributes.Add(data, rel)
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || hasS(metadataFilenames, d.Name()) || ignore.Ignored(rel, false) {
+			return nil
+		}
+		la := attributes.Resolve(rel)
+		if override(la.Vendored, IsVendored(rel)) || override(la.Documentation, isDocumentation(rel)) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 || override(la.Binary, DetectEncoding(data) == EncodingBinary) || override(la.Generated, IsGenerated(rel, data)) {
+			return nil
+		}
+		m := la.Language
+		if !la.HasLanguage {
+			// The whole path is used, so that path rules like debian/rules apply
+			m = DetectFile(p, data)
+			if m == Ignore {
+				return nil
+			}
+		}
+		if m != Blank {
+			perMode[m] += int64(len(data))
+			b.Total += int64(len(data))
+		}
+		return nil
+	})
+	if err != nil {
+		return Breakdown{}, err
+	}
+	for m, size := range perMode {
+		b.Languages = append(b.Languages, LanguageShare{Mode: m, Bytes: size, Percent: 100 * float64(size) / float64(b.Total)})
+	}
+	slices.SortFunc(b.Languages, func(x, y LanguageShare) int {
+		if x.Bytes != y.Bytes {
+			if x.Bytes > y.Bytes {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(x.Mode.String(), y.Mode.String())
+	})
+	if len(b.Languages) > 0 {
+		b.Primary = b.Languages[0].Mode
+	}
+	return b, nil
+}
@@ -0,0 +1,79 @@
+package mode
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAnalyze(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":                 {Data: []byte(strings.Repeat("package main\n", 7))},
+		"run.sh":                  {Data: []byte("#!/bin/sh\necho hi\n")},
+		"notes.md":                {Data: []byte("# Notes\n")},
+		"README.md":               {Data: []byte(strings.Repeat("# Readme\n", 100))},
+		"docs/index.html":         {Data: []byte(strings.Repeat("<html></html>\n", 100))},
+		"vendor/x/x.go":           {Data: []byte(strings.Repeat("package x\n", 100))},
+		"node_modules/x/index.js": {Data: []byte(strings.Repeat("var x = 1;\n", 100))},
+		"gen.go":                  {Data: []byte("// Code generated by stringer; DO NOT EDIT.\n\npackage main\n" + strings.Repeat("\n", 1000))},
+		"logo.png":                {Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00")},
+		"unknown.xyzw":            {Data: []byte("???")},
+		".gitignore":              {Data: []byte("out/\n")},
+		".gitattributes":          {Data: []byte("*.sh text eol=lf\n")},
+		".dockerignore":           {Data: []byte("out/\n")},
+		"debian/rules":            {Data: []byte("%:\n\tdh $@\n")},
+		"out/main.js":             {Data: []byte(strings.Repeat("var y = 2;\n", 100))},
+	}
+	b, err := Analyze(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Primary != Go {
+		t.Fatalf("Expected Go as the primary language, got %s", b.Primary)
+	}
+	if len(b.Languages) != 4 {
+		t.Fatalf("Expected 4 languages, got %v", b.Languages)
+	}
+	if !slices.ContainsFunc(b.Languages, func(share LanguageShare) bool { return share.Mode == Make }) {
+		t.Errorf("Expected debian/rules to be counted as Make, got %v", b.Languages)
+	}
+	var sum float64
+	for _, share := range b.Languages {
+		sum += share.Percent
+		if share.Mode == JavaScript || share.Mode == HTML || share.Mode == Ignore {
+			t.Errorf("%s should have been excluded", share.Mode)
+		}
+	}
+	if sum < 99.9 || sum > 100.1 {
+		t.Errorf("Expected the percentages to add up to 100, got %f", sum)
+	}
+}
+
+func TestAnalyzeDocumentation(t *testing.T) {
+	fsys := fstest.MapFS{
+		"history.go":       {Data: []byte("package main\n")},
+		"license_check.go": {Data: []byte("package main\n")},
+		"main.py":          {Data: []byte("print(1)\n")},
+		"LICENSE":          {Data: []byte(strings.Repeat("Permission is hereby granted\n", 100))},
+		"CHANGELOG.rst":    {Data: []byte(strings.Repeat("Changes\n", 100))},
+		"README.md":        {Data: []byte(strings.Repeat("# Readme\n", 100))},
+	}
+	b, err := Analyze(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Languages) != 2 || b.Languages[0].Mode != Go || b.Languages[0].Bytes != 26 || b.Languages[1].Mode != Python {
+		t.Errorf("Expected history.go and license_check.go to be counted as Go, and main.py as Python, got %v", b.Languages)
+	}
+	for _, name := range []string{"README", "readme.md", "LICENSE.txt", "CHANGELOG.rst", "docs/api.go"} {
+		if !isDocumentation(name) {
+			t.Errorf("Expected %s to be documentation", name)
+		}
+	}
+	for _, name := range []string{"history.go", "license_check.go", "readme_parser.go", "changes.go", "news.go", "authorship.rs"} {
+		if isDocumentation(name) {
+			t.Errorf("Expected %s not to be documentation", name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package mode
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Compression is a compression format that can wrap the contents of a file
+type Compression int
+
+const (
+	CompressionNone  Compression = iota // not compressed
+	CompressionGzip                     // .gz
+	CompressionBzip2                    // .bz2
+	CompressionXZ                       // .xz
+	CompressionZstd                     // .zst
+	CompressionLZ4                      // .lz4
+)
+
+// compressionSuffixes maps filename extensions to compression formats
+var compressionSuffixes = map[string]Compression{
+	".gz":  CompressionGzip,
+	".bz2": CompressionBzip2,
+	".xz":  CompressionXZ,
+	".zst": CompressionZstd,
+	".lz4": CompressionLZ4,
+}
+
+// DecompressLimit is the maximum number of bytes that DetectReader reads,
+// and the maximum number of decompressed bytes it looks at.
+// Set it to 0 to make DetectReader only look at the compressed data.
+var DecompressLimit = 64 * 1024
+
+// String returns a short lowercase string representing the given compression format
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionBzip2:
+		return "bzip2"
+	case CompressionXZ:
+		return "xz"
+	case CompressionZstd:
+		return "zstd"
+	case CompressionLZ4:
+		return "lz4"
+	default:
+		return "?"
+	}
+}
+
+// StripCompression removes a known compression suffix, like ".gz", from the given filename.
+// Returns the inner filename and the compression format, or the unchanged filename and CompressionNone.
+func StripCompression(filename string) (string, Compression) {
+	ext := filepath.Ext(filename)
+	if c, ok := compressionSuffixes[strings.ToLower(ext)]; ok {
+		return strings.TrimSuffix(filename, ext), c
+	}
+	return filename, CompressionNone
+}
+
+// DetectCompression looks at the magic number at the start of the given data to find the compression format
+func DetectCompression(data []byte) Compression {
+	switch _, mime := DetectMagic(data); mime {
+	case "application/gzip":
+		return CompressionGzip
+	case "application/x-bzip2":
+		return CompressionBzip2
+	case "application/x-xz":
+		return CompressionXZ
+	case "application/zstd":
+		return CompressionZstd
+	case "application/x-lz4":
+		return CompressionLZ4
+	}
+	return CompressionNone
+}
+
+// DetectReader reads the start of the given reader, up to DecompressLimit bytes, and tries to find the Mode.
+// If the data is compressed with gzip or bzip2, the start of it is decompressed and the Mode of the
+// decompressed data is returned together with the compression format. For compression formats that
+// can not be decompressed with the standard library, Blank is returned together with the format.
+func DetectReader(r io.Reader) (Mode, Compression, error) {
+	limit := DecompressLimit
+	if limit <= 0 {
+		limit = 64 * 1024
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)))
+	if err != nil {
+		return Blank, CompressionNone, err
+	}
+	c := DetectCompression(data)
+	if c == CompressionNone || DecompressLimit <= 0 {
+		return SimpleDetectBytes(data), c, nil
+	}
+	var zr io.Reader
+	switch c {
+	case CompressionGzip:
+		gzr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return Blank, c, err
+		}
+		zr = gzr
+	case CompressionBzip2:
+		zr = bzip2.NewReader(bytes.NewReader(data))
+	default:
+		return Blank, c, nil
+	}
+	// The compressed data may be cut off, so keep what could be decompressed
+	inner, err := io.ReadAll(io.LimitReader(zr, int64(DecompressLimit)))
+	if err != nil && len(inner) == 0 {
+		return Blank, c, err
+	}
+	return SimpleDetectBytes(inner), c, nil
+}
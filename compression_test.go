@@ -0,0 +1,58 @@
+package mode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"testing"
+)
+
+func TestStripCompression(t *testing.T) {
+	if name, c := StripCompression("app.log.gz"); name != "app.log" || c != CompressionGzip {
+		t.Fail()
+	}
+	if name, c := StripCompression("main.go"); name != "main.go" || c != CompressionNone {
+		t.Fail()
+	}
+	compressed := map[string]Mode{
+		"app.log.gz":    Log,
+		"dump.sql.xz":   SQL,
+		"data.csv.zst":  CSV,
+		"main.go.bz2":   Go,
+		"notes.md.lz4":  Markdown,
+		"backup.tar.gz": Archive,
+		"unknown.gz":    Archive,
+	}
+	for filename, expected := range compressed {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
+
+func TestDetectReader(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("#!/bin/bash\necho hello\n"))
+	zw.Close()
+	if m, c, err := DetectReader(bytes.NewReader(buf.Bytes())); err != nil || m != Shell || c != CompressionGzip {
+		t.Errorf("Expected Shell and gzip, got %s and %s (%v)", m, c, err)
+	}
+	// The same data, cut off in the middle of the compressed stream
+	if m, c, err := DetectReader(bytes.NewReader(buf.Bytes()[:buf.Len()-8])); err != nil || m != Shell || c != CompressionGzip {
+		t.Errorf("Expected Shell and gzip, got %s and %s (%v)", m, c, err)
+	}
+	bz2, _ := hex.DecodeString("425a68393141592653598f4dcef70000025180001068009a618800200022980190806803304bc8b0e74e92b7c2ee48a70a1211e9b9dee0")
+	if m, c, err := DetectReader(bytes.NewReader(bz2)); err != nil || m != Shell || c != CompressionBzip2 {
+		t.Errorf("Expected Shell and bzip2, got %s and %s (%v)", m, c, err)
+	}
+	if m, c, err := DetectReader(bytes.NewReader([]byte("#!/usr/bin/env python3\n"))); err != nil || m != Python || c != CompressionNone {
+		t.Errorf("Expected Python, got %s and %s (%v)", m, c, err)
+	}
+	if m, c, _ := DetectReader(bytes.NewReader([]byte("\xfd7zXZ\x00\x00\x04"))); m != Blank || c != CompressionXZ {
+		t.Errorf("Expected Blank and xz, got %s and %s", m, c)
+	}
+	if m := DetectFile("app.log.gz", buf.Bytes()); m != Log {
+		t.Errorf("Expected Log, got %s", m)
+	}
+}
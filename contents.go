@@ -2,13 +2,21 @@ package mode
 
 import (
 	"bytes"
+	"path/filepath"
 	"strings"
 
 	"github.com/xyproto/lookslikegoasm"
 )
 
-// SimpleDetectBytes tries to return a Mode given a byte slice of file contents
+// SimpleDetectBytes tries to return a Mode given a byte slice of file contents.
+// Binary data that is not recognized gives the Binary mode.
 func SimpleDetectBytes(contents []byte) Mode {
+	m, _ := DetectBytes(contents)
+	return m
+}
+
+// detectText tries to return a Mode given a byte slice of UTF-8 text, without a byte order mark
+func detectText(contents []byte) Mode {
 	nl := []byte("\n")
 	firstLine := contents
 	if bytes.Contains(contents, nl) {
@@ -36,6 +44,7 @@ func SimpleDetect(contents string) Mode {
 // Returns true if a mode is found.
 func DetectFromContentBytes(initial Mode, firstLine []byte, allBytesFunc func() []byte) (Mode, bool) {
 	var found, notConfig bool
+	firstLine = bytes.TrimPrefix(firstLine, utf8BOM)
 	m := initial
 	if m == Assembly || m == Blank {
 		// Go/Plan9 style Assembly
@@ -73,6 +82,9 @@ func DetectFromContentBytes(initial Mode, firstLine []byte, allBytesFunc func()
 		return Shell, true
 	} else if bytes.HasPrefix(firstLine, []byte("<?xml ")) {
 		return XML, true
+	} else if bytes.HasPrefix(firstLine, []byte("<")) && bytes.Contains(firstLine, []byte(" xmlns")) && !bytes.HasPrefix(bytes.ToLower(firstLine), []byte("<html")) {
+		// A root element with an XML namespace, like <svg xmlns="http://www.w3.org/2000/svg">
+		return XML, true
 	} else if bytes.HasPrefix(firstLine, []byte("{\"")) {
 		return JSON, true
 	} else if bytes.HasPrefix(firstLine, []byte("{\\rtf")) {
@@ -107,22 +119,27 @@ func DetectFromContentBytes(initial Mode, firstLine []byte, allBytesFunc func()
 	} else if bytes.HasPrefix(firstLine, []byte("@vertex")) || bytes.HasPrefix(firstLine, []byte("@fragment")) || bytes.HasPrefix(firstLine, []byte("@compute")) {
 		return WGSL, true
 	}
+	if (m == Blank || m == Text) && !notConfig {
+		data := allBytesFunc()
+		// Log files with other names than *.log, like syslog or app.out. JSON lines are left as JSON.
+		// This is checked before CSV and Config, since log lines often contain commas and "=".
+		if f := DetectLogFormat(data); f != LogFormatNone && f != LogFormatJSON {
+			return Log, true
+		}
+		// Rows of delimited data, with the same number of fields on every row
+		if m == Blank && looksLikeCSV(data) {
+			return CSV, true
+		}
+	}
 	// If more lines start with "# " than "// " or "/* ", and mode is blank,
 	// set the mode to Config and enable syntax highlighting.
 	if !notConfig && (m == Blank || m == Config || m == Markdown || m == Nix) {
 		foundFirstContent := false
-		hashComment := 0
-		slashComment := 0
 		reStructuredTextMarkers := 0
 		byteLines := bytes.Split(allBytesFunc(), []byte("\n"))
 		configMarkers := 0
 		prevLineWasText := false
 		for _, line := range byteLines {
-			if bytes.HasPrefix(line, []byte("# ")) {
-				hashComment++
-			} else if bytes.HasPrefix(line, []byte("/")) { // Count all lines starting with "/" as a comment, for this purpose
-				slashComment++
-			}
 			trimmedLine := bytes.TrimSpace(line)
 			if len(trimmedLine) > 1 && ((trimmedLine[0] == byte(':') && trimmedLine[1] == byte(':')) || bytes.HasPrefix(trimmedLine, []byte(".. ")) || bytes.HasPrefix(trimmedLine, []byte("[source,"))) {
 				reStructuredTextMarkers++
@@ -159,7 +176,7 @@ func DetectFromContentBytes(initial Mode, firstLine []byte, allBytesFunc func()
 		if (m == Blank || m == Config) && looksLikeShell(byteLines) {
 			return Shell, true
 		}
-		if hashComment > slashComment {
+		if hashComment, slashComment := countComments(allBytesFunc()); hashComment > slashComment {
 			return Config, true
 		}
 		// Are "most of the lines" containing (, ) or = ?
@@ -257,3 +274,100 @@ func rstAdornment(trimmedLine []byte) bool {
 	}
 	return true
 }
+
+// cPreprocessor are the C preprocessor directives, which are not "#" comments
+var cPreprocessor = []string{"define", "elif", "else", "endif", "error", "if", "ifdef", "ifndef", "import", "include", "pragma", "undef"}
+
+// countComments classifies the lines of the given data with both "#" comments and C-style comments,
+// and returns the number of "#" comment lines and "//" or "/* */" comment lines
+func countComments(data []byte) (hashComments, slashComments int) {
+	lines := bytes.Split(data, []byte("\n"))
+	for i, kind := range Mode(Config).ClassifyLines(data) {
+		if kind != CommentLine {
+			continue
+		}
+		directive := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(lines[i]), []byte("#")))
+		if word, _, _ := bytes.Cut(directive, []byte(" ")); !hasS(cPreprocessor, string(word)) {
+			hashComments++
+		}
+	}
+	for _, kind := range Mode(C).ClassifyLines(data) {
+		if kind == CommentLine {
+			slashComments++
+		}
+	}
+	return hashComments, slashComments
+}
+
+// DetectFile tries to return a Mode given both a filename and the file contents.
+// The filename is looked at first, then the contents are used to find or refine the mode.
+// Byte order marks are stripped and UTF-16 and UTF-32 text is decoded before the contents are looked at.
+// Binary data gives the Binary mode, unless the filename or contents point to a known binary format.
+func DetectFile(filename string, data []byte) Mode {
+	m := Detect(filename)
+	text, enc := DecodeUTF8(data)
+	if enc == EncodingBinary {
+		magicMode := detectBinary(data)
+		// Trust the filename over a generic magic number, like for .docx files that are also ZIP files,
+		// or for compressed files where the filename gives the inner mode, like app.log.gz
+		if _, c := StripCompression(filename); c != CompressionNone && magicMode == Archive {
+			return m
+		}
+		if !binaryFormat(m) || (magicMode != Archive && magicMode != Binary) {
+			return magicMode
+		}
+		return m
+	}
+	if hasS(documentFilenames, filepath.Base(filename)) {
+		return detectDocument(text)
+	}
+	firstLine := text
+	if i := bytes.IndexByte(text, '\n'); i >= 0 {
+		firstLine = text[:i]
+	}
+	if len(firstLine) > 512 { // just look at the first 512, if it's one long line
+		firstLine = firstLine[:512]
+	}
+	if contentMode, found := DetectFromContentBytes(m, firstLine, func() []byte { return text }); found {
+		return contentMode
+	}
+	return m
+}
+
+// documentFilenames are conventional documentation files without an extension,
+// where the contents decide if they are Markdown, reStructuredText or plain text
+var documentFilenames = []string{"CHANGELOG", "CHANGES", "CONTRIBUTING", "HACKING", "HISTORY", "NEWS", "README", "TODO"}
+
+// detectDocument looks for Markdown and reStructuredText markup in the given text.
+// Returns Markdown, ReStructured or Text.
+func detectDocument(text []byte) Mode {
+	var markdownMarkers, rstMarkers int
+	prevLineWasText := false
+	for _, line := range bytes.Split(text, []byte("\n")) {
+		trimmedLine := bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(line, []byte("# ")) || bytes.HasPrefix(line, []byte("## ")) || bytes.HasPrefix(line, []byte("```")):
+			markdownMarkers++
+		case bytes.HasPrefix(trimmedLine, []byte("> ")) || bytes.Contains(line, []byte("](")) || bytes.Contains(line, []byte("**")):
+			markdownMarkers++
+		case bytes.HasPrefix(trimmedLine, []byte(".. ")) || bytes.HasSuffix(trimmedLine, []byte("::")) || bytes.Contains(line, []byte("`_")):
+			rstMarkers++
+		case rstAdornment(trimmedLine) && prevLineWasText:
+			// "=" and "-" underlines are used for titles in both Markdown and reStructuredText
+			if trimmedLine[0] == '=' || trimmedLine[0] == '-' {
+				markdownMarkers++
+				rstMarkers++
+			} else {
+				rstMarkers += 2
+			}
+		}
+		prevLineWasText = len(trimmedLine) > 0 && !rstAdornment(trimmedLine)
+	}
+	switch {
+	case rstMarkers > markdownMarkers:
+		return ReStructured
+	case markdownMarkers > 0:
+		return Markdown
+	}
+	return Text
+}
@@ -32,6 +32,9 @@ var examples = map[string]Mode{
 	"amends \"package://example.com/config.pkl\"\n":     Pkl,
 	"@vertex\nfn vs_main() -> vec4<f32> {\n}\n":         WGSL,
 	"@fragment\nfn fs_main() -> vec4<f32> {\n}\n":       WGSL,
+	"#port=22\n#host=localhost\nverbose\n":              Config, // "#" comments without a space
+	"#define N 1\n#undef M\n/* a */\n":                  Blank,  // preprocessor directives are not comments
+	"/* header\n * more\n * more\n */\n# x\n# y\n":      Blank,  // block comments count as comment lines
 }
 
 var exampleFiles = map[string]Mode{
@@ -79,3 +82,22 @@ func TestSimpleDetectFromBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectDocument(t *testing.T) {
+	documents := []struct {
+		filename string
+		data     string
+		mode     Mode
+	}{
+		{"README", "# Project\n\nSee [the docs](https://example.com).\n", Markdown},
+		{"README", "Project\n=======\n\n.. code-block:: sh\n\n   make\n", ReStructured},
+		{"NEWS", "Version 1.2\n\n  Fixed a bug in the parser.\n", Text},
+		{"CHANGELOG", "Changes\n*******\n\n1.0\n~~~\n", ReStructured},
+		{"docs/README", "Title\n-----\n\n```sh\nmake\n```\n", Markdown},
+	}
+	for _, d := range documents {
+		if m := DetectFile(d.filename, []byte(d.data)); m != d.mode {
+			t.Errorf("Expected %s for %s with %q, got %s", Mode(d.mode), d.filename, d.data, m)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package mode
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSVEscape is the way quote characters are escaped within quoted CSV fields
+type CSVEscape int
+
+const (
+	CSVEscapeNone      CSVEscape = iota // no escaped quotes were found
+	CSVEscapeDoubled                    // "" within a quoted field, as in RFC 4180
+	CSVEscapeBackslash                  // \" within a quoted field
+)
+
+// Dialect describes the format of CSV or TSV data, as found by SniffCSV
+type Dialect struct {
+	Delimiter   rune      // ',', '\t', ';' or '|'
+	Quote       rune      // '"' or '\'', or 0 if no fields are quoted
+	Escape      CSVEscape // how quotes are escaped within quoted fields
+	Header      bool      // true if the first row is most likely a header
+	HeaderScore float64   // how likely it is that the first row is a header, from 0 to 1
+	Columns     int       // the most common number of fields per row
+	LineEnding  string    // "\n" or "\r\n"
+}
+
+// CSVSniffLimit is the number of bytes at the start of the data that SniffCSV looks at
+var CSVSniffLimit = 64 * 1024
+
+// ErrNotCSV is returned by SniffCSV when the data does not look like delimited data
+var ErrNotCSV = errors.New("not delimited data")
+
+// csvDelimiters are the delimiters that SniffCSV considers, in order of preference
+var csvDelimiters = []byte{',', '\t', ';', '|'}
+
+// String returns a short string representing the given escape style
+func (e CSVEscape) String() string {
+	switch e {
+	case CSVEscapeNone:
+		return "none"
+	case CSVEscapeDoubled:
+		return "doubled"
+	case CSVEscapeBackslash:
+		return "backslash"
+	default:
+		return "?"
+	}
+}
+
+// SniffCSV looks at the start of the given data, up to CSVSniffLimit bytes, and finds the delimiter,
+// quoting, escaping, header row and line endings. Returns ErrNotCSV if no delimiter gives at
+// least two columns for most of the rows.
+func SniffCSV(data []byte) (Dialect, error) {
+	data = sniffPrefix(data, CSVSniffLimit)
+	d := Dialect{LineEnding: "\n"}
+	if bytes.Count(data, []byte("\r\n")) > bytes.Count(data, []byte("\n"))/2 {
+		d.LineEnding = "\r\n"
+	}
+	bestScore := 0.0
+	for _, delimiter := range csvDelimiters {
+		quote := csvQuote(data, delimiter)
+		records, _ := parseCSV(data, delimiter, quote)
+		columns, consistency := csvColumns(records)
+		if columns < 2 {
+			continue
+		}
+		// Prefer consistent rows, and then more columns
+		if score := consistency + float64(columns)/1000; score > bestScore+0.05 {
+			bestScore = score
+			d.Delimiter, d.Quote, d.Columns = rune(delimiter), rune(quote), columns
+		}
+	}
+	if bestScore < 0.8 {
+		return d, ErrNotCSV
+	}
+	var records [][]string
+	records, d.Escape = parseCSV(data, byte(d.Delimiter), byte(d.Quote))
+	d.HeaderScore = csvHeaderScore(records, d.Columns)
+	d.Header = d.HeaderScore > 0.5
+	return d, nil
+}
+
+// sniffPrefix returns the start of the given data, up to limit bytes and without a byte order mark.
+// The last line is skipped if it is cut off.
+func sniffPrefix(data []byte, limit int) []byte {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if len(data) > limit {
+		data = data[:limit]
+		// Skip the last line, since it is most likely cut off
+		if i := bytes.LastIndexByte(data, '\n'); i > 0 {
+			data = data[:i+1]
+		}
+	}
+	return data
+}
+
+// csvQuote returns the quote character that is used at the start of fields, or 0
+func csvQuote(data []byte, delimiter byte) byte {
+	var best byte
+	bestCount := 0
+	for _, quote := range []byte{'"', '\''} {
+		count := bytes.Count(data, []byte{delimiter, quote}) + bytes.Count(data, []byte{'\n', quote})
+		if bytes.HasPrefix(data, []byte{quote}) {
+			count++
+		}
+		if count > bestCount {
+			best, bestCount = quote, count
+		}
+	}
+	return best
+}
+
+// parseCSV splits the given data into records, with quote as the quote character, or 0 for no quoting.
+// Quotes within quoted fields may be doubled or escaped with a backslash.
+// Returns the records and the escape style that was found.
+func parseCSV(data []byte, delimiter, quote byte) ([][]string, CSVEscape) {
+	var (
+		records  [][]string
+		record   []string
+		field    strings.Builder
+		quoted   bool
+		escape   = CSVEscapeNone
+		atStart  = true // at the start of a field
+		hasField bool
+	)
+	endField := func() {
+		record = append(record, field.String())
+		field.Reset()
+		atStart = true
+	}
+	endRecord := func() {
+		if hasField || len(record) > 0 {
+			endField()
+			records = append(records, record)
+		}
+		record, hasField = nil, false
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(data) && data[i+1] == quote:
+			escape = CSVEscapeBackslash
+			field.WriteByte(quote)
+			i++
+		case quoted && c == quote && i+1 < len(data) && data[i+1] == quote:
+			escape = CSVEscapeDoubled
+			field.WriteByte(quote)
+			i++
+		case quoted && c == quote:
+			quoted = false
+		case quoted:
+			field.WriteByte(c)
+		case atStart && quote != 0 && c == quote:
+			quoted, atStart, hasField = true, false, true
+		case c == delimiter:
+			endField()
+			hasField = true
+		case c == '\n':
+			endRecord()
+		case c == '\r' && i+1 < len(data) && data[i+1] == '\n':
+		default:
+			field.WriteByte(c)
+			atStart, hasField = false, true
+		}
+	}
+	endRecord()
+	return records, escape
+}
+
+// csvColumns returns the most common number of fields in the given records,
+// and the fraction of the records that have that number of fields
+func csvColumns(records [][]string) (int, float64) {
+	if len(records) == 0 {
+		return 0, 0
+	}
+	counts := make(map[int]int)
+	for _, record := range records {
+		counts[len(record)]++
+	}
+	columns, count := 0, 0
+	for n, c := range counts {
+		if c > count || (c == count && n > columns) {
+			columns, count = n, c
+		}
+	}
+	return columns, float64(count) / float64(len(records))
+}
+
+// csvHeaderScore returns how likely it is that the first record is a header, from 0 to 1.
+// Each column votes for a header if the first value differs from the values below it, by being
+// text above numbers or by having a different length than values that all have the same length,
+// and votes against a header if the first value is like the values below it.
+func csvHeaderScore(records [][]string, columns int) float64 {
+	if len(records) < 2 || len(records[0]) != columns {
+		return 0
+	}
+	votes := 0
+	for col, value := range records[0] {
+		value = strings.TrimSpace(value)
+		numbers, total, length := 0, 0, -1
+		for _, record := range records[1:] {
+			if col >= len(record) {
+				continue
+			}
+			cell := strings.TrimSpace(record[col])
+			if cell == "" {
+				continue
+			}
+			total++
+			if isNumber(cell) {
+				numbers++
+			}
+			switch n := utf8.RuneCountInString(cell); {
+			case length == -1:
+				length = n
+			case length != n:
+				length = -2 // the lengths vary
+			}
+		}
+		switch {
+		case total == 0:
+		case numbers == total:
+			if isNumber(value) {
+				votes--
+			} else {
+				votes++
+			}
+		case length >= 0:
+			if utf8.RuneCountInString(value) == length {
+				votes--
+			} else {
+				votes++
+			}
+		}
+	}
+	score := 0.5 + float64(votes)/float64(2*columns)
+	return max(0, min(1, score))
+}
+
+// isNumber checks if the given string is an integer or a decimal number, possibly with a sign or a percent sign
+func isNumber(s string) bool {
+	s = strings.TrimSuffix(s, "%")
+	_, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	return err == nil
+}
+
+// looksLikeCSV checks if the start of the given text is delimited data with at least three rows,
+// where all rows have the same number of fields. There must be a header row or at least three columns.
+// Prose with commas, Markdown tables and tab-indented lines are not counted.
+func looksLikeCSV(text []byte) bool {
+	text = sniffPrefix(text, CSVSniffLimit)
+	d, err := SniffCSV(text)
+	if err != nil || (!d.Header && d.Columns < 3) {
+		return false
+	}
+	records, _ := parseCSV(text, byte(d.Delimiter), byte(d.Quote))
+	if len(records) < 3 {
+		return false
+	}
+	spaced := 0
+	firstEmpty, filled := true, false
+	for _, record := range records {
+		if len(record) != d.Columns {
+			return false
+		}
+		if record[0] == "" && record[len(record)-1] == "" {
+			// Like a Markdown table: | a | b |
+			return false
+		}
+		nonEmpty := 0
+		for _, field := range record {
+			if field != "" {
+				nonEmpty++
+			}
+		}
+		firstEmpty = firstEmpty && record[0] == ""
+		filled = filled || nonEmpty >= 2
+		for _, field := range record[1:] {
+			if strings.HasPrefix(field, " ") {
+				spaced++
+			}
+		}
+	}
+	// Indented code has an empty first field, and data should have at least one row with two values
+	if firstEmpty || !filled {
+		return false
+	}
+	// Fields that start with a space are common in prose, but not in data
+	return d.Delimiter == '\t' || spaced*2 < len(records)*(d.Columns-1)
+}
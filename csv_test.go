@@ -0,0 +1,82 @@
+package mode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSniffCSV(t *testing.T) {
+	dialects := []struct {
+		data      string
+		delimiter rune
+		quote     rune
+		escape    CSVEscape
+		header    bool
+		columns   int
+	}{
+		{"name,age,city\nAlice,30,Oslo\nBob,25,Bergen\n", ',', 0, CSVEscapeNone, true, 3},
+		{"id\tprice\n1\t9.50\n2\t12.00\n", '\t', 0, CSVEscapeNone, true, 2},
+		{"navn;beløp\nKari;1,5\nOla;2,75\n", ';', 0, CSVEscapeNone, true, 2},
+		{"a|b|c\n1|2|3\n4|5|6\n", '|', 0, CSVEscapeNone, true, 3},
+		{"\"title\",\"quote\"\n\"Dune\",\"He said \"\"no\"\"\"\n\"Emma\",\"a, b\"\n", ',', '"', CSVEscapeDoubled, true, 2},
+		{"'x','y'\n'it\\'s','ok'\n'a','b'\n", ',', '\'', CSVEscapeBackslash, false, 2},
+		{"1,2,3\n4,5,6\n7,8,9\n", ',', 0, CSVEscapeNone, false, 3},
+		{"\"note\",\"n\"\n\"two\nlines\",1\n\"one line\",2\n", ',', '"', CSVEscapeNone, true, 2},
+	}
+	for _, d := range dialects {
+		dialect, err := SniffCSV([]byte(d.data))
+		if err != nil {
+			t.Errorf("Expected no error for %q, got %v", d.data, err)
+			continue
+		}
+		if dialect.Delimiter != d.delimiter || dialect.Quote != d.quote || dialect.Escape != d.escape || dialect.Header != d.header || dialect.Columns != d.columns {
+			t.Errorf("Unexpected dialect for %q: %+v", d.data, dialect)
+		}
+		if dialect.LineEnding != "\n" {
+			t.Errorf("Expected \\n as the line ending for %q", d.data)
+		}
+	}
+	if dialect, err := SniffCSV([]byte("a,b\r\n1,2\r\n")); err != nil || dialect.LineEnding != "\r\n" {
+		t.Errorf("Expected \\r\\n as the line ending, got %+v, %v", dialect, err)
+	}
+	for _, data := range []string{"", "hello\nthere\n", "just one line of text\n"} {
+		if _, err := SniffCSV([]byte(data)); err != ErrNotCSV {
+			t.Errorf("Expected ErrNotCSV for %q, got %v", data, err)
+		}
+	}
+}
+
+func TestSniffCSVLimit(t *testing.T) {
+	data := "a,b\n" + strings.Repeat("1,2\n", CSVSniffLimit/4) + strings.Repeat("x\n", CSVSniffLimit)
+	if dialect, err := SniffCSV([]byte(data)); err != nil || dialect.Columns != 2 {
+		t.Errorf("Expected only the start of the data to be sniffed, got %+v, %v", dialect, err)
+	}
+}
+
+func TestLooksLikeCSVLimit(t *testing.T) {
+	data := "a,b\n" + strings.Repeat("1,2\n", CSVSniffLimit/4) + strings.Repeat("x\n", CSVSniffLimit)
+	if !looksLikeCSV([]byte(data)) {
+		t.Error("Expected only the start of the data to be checked")
+	}
+}
+
+func TestDetectCSVContents(t *testing.T) {
+	contents := map[string]Mode{
+		"name,age\nAlice,30\nBob,25\n":                     CSV,
+		"x\ty\tz\n1\t2\t3\n4\t5\t6\n":                      CSV,
+		"Hello, world\nGood night, moon\nFoo, bar\n":       Blank,
+		"| a | b |\n|---|---|\n| 1 | 2 |\n":                Blank,
+		"a,b\n1,2\n":                                       Blank,
+		"name,age\nAlice,30\nBob,25,extra\nCarol,40\n":     Blank,
+		"<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>": XML,
+		"\tgo build\n\tgo test\n\tgo vet\n":                Blank,
+		"a,\nb,\nc,\n":                                     Blank,
+		"echo a,b\necho c,d\necho e,f\n":                   Blank,
+		"1,2,3\n4,5,6\n7,8,9\n":                            CSV,
+	}
+	for data, expected := range contents {
+		if m := SimpleDetect(data); m != expected {
+			t.Errorf("Expected %s for %q, got %s", Mode(expected), data, m)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package mode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the text encoding of file contents, as found by DetectEncoding
+type Encoding int
+
+const (
+	EncodingUTF8    Encoding = iota // UTF-8 without a BOM, or plain ASCII
+	EncodingUTF8BOM                 // UTF-8 with a byte order mark
+	EncodingUTF16LE                 // UTF-16, little endian, with a byte order mark
+	EncodingUTF16BE                 // UTF-16, big endian, with a byte order mark
+	EncodingUTF32LE                 // UTF-32, little endian, with a byte order mark
+	EncodingUTF32BE                 // UTF-32, big endian, with a byte order mark
+	EncodingLatin1                  // not valid UTF-8, most likely ISO-8859-1 or Windows-1252
+	EncodingBinary                  // not text
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+	utf32LEBOM = []byte{0xff, 0xfe, 0x00, 0x00}
+	utf32BEBOM = []byte{0x00, 0x00, 0xfe, 0xff}
+)
+
+// String returns a short string representing the given encoding
+func (enc Encoding) String() string {
+	switch enc {
+	case EncodingUTF8:
+		return "UTF-8"
+	case EncodingUTF8BOM:
+		return "UTF-8 with BOM"
+	case EncodingUTF16LE:
+		return "UTF-16LE"
+	case EncodingUTF16BE:
+		return "UTF-16BE"
+	case EncodingUTF32LE:
+		return "UTF-32LE"
+	case EncodingUTF32BE:
+		return "UTF-32BE"
+	case EncodingLatin1:
+		return "ISO-8859-1"
+	case EncodingBinary:
+		return "binary"
+	default:
+		return "?"
+	}
+}
+
+// DetectEncoding looks at the start of the given data and tries to find the text encoding.
+// Byte order marks are recognized, and data with NUL bytes, many control characters or
+// the magic number of a known binary file format is reported as EncodingBinary.
+func DetectEncoding(data []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return EncodingUTF8BOM
+	case bytes.HasPrefix(data, utf32LEBOM): // must come before UTF-16LE
+		return EncodingUTF32LE
+	case bytes.HasPrefix(data, utf32BEBOM):
+		return EncodingUTF32BE
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return EncodingUTF16BE
+	}
+	if m, _ := DetectMagic(data); m != Blank {
+		return EncodingBinary
+	}
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	controlCount := 0
+	for _, b := range data {
+		switch {
+		case b == 0:
+			return EncodingBinary
+		case b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v' && b != '\b' && b != 0x1b:
+			controlCount++
+		}
+	}
+	if controlCount*10 > len(data) {
+		return EncodingBinary
+	}
+	if !validUTF8Prefix(data) {
+		return EncodingLatin1
+	}
+	return EncodingUTF8
+}
+
+// validUTF8Prefix checks if the given data is valid UTF-8, allowing the last rune to be
+// incomplete, since data may be the first part of a larger file
+func validUTF8Prefix(data []byte) bool {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				data = data[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(data)
+}
+
+// DecodeUTF8 detects the encoding of the given data and returns the data as UTF-8, without any byte order mark.
+// Binary data is returned unchanged.
+func DecodeUTF8(data []byte) ([]byte, Encoding) {
+	enc := DetectEncoding(data)
+	switch enc {
+	case EncodingUTF8BOM:
+		return data[len(utf8BOM):], enc
+	case EncodingUTF16LE, EncodingUTF16BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		if enc == EncodingUTF16BE {
+			order = binary.BigEndian
+		}
+		data = data[2:]
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[i*2:])
+		}
+		return []byte(string(utf16.Decode(units))), enc
+	case EncodingUTF32LE, EncodingUTF32BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		if enc == EncodingUTF32BE {
+			order = binary.BigEndian
+		}
+		data = data[4:]
+		runes := make([]rune, len(data)/4)
+		for i := range runes {
+			runes[i] = rune(order.Uint32(data[i*4:]))
+		}
+		return []byte(string(runes)), enc
+	case EncodingLatin1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return []byte(string(runes)), enc
+	}
+	return data, enc
+}
+
+// DetectBytes tries to return a Mode and an Encoding given a byte slice of file contents.
+// Byte order marks are stripped and UTF-16 and UTF-32 text is decoded before the text heuristics run.
+// Binary data that is not recognized gives the Binary mode.
+func DetectBytes(contents []byte) (Mode, Encoding) {
+	text, enc := DecodeUTF8(contents)
+	if enc == EncodingBinary {
+		return detectBinary(contents), enc
+	}
+	return detectText(text), enc
+}
@@ -0,0 +1,50 @@
+package mode
+
+import (
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	encodings := map[string]Encoding{
+		"hello\n":                             EncodingUTF8,
+		"\xef\xbb\xbf#!/bin/sh\n":             EncodingUTF8BOM,
+		"\xff\xfe#\x00!\x00":                  EncodingUTF16LE,
+		"\xfe\xff\x00#\x00!":                  EncodingUTF16BE,
+		"\xff\xfe\x00\x00#\x00\x00\x00":       EncodingUTF32LE,
+		"caf\xe9\n":                           EncodingLatin1,
+		"\x7fELF\x02\x01\x01":                 EncodingBinary,
+		"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR": EncodingBinary,
+		"text\x00with a NUL":                  EncodingBinary,
+		"bl\xc3\xa5b\xc3":                     EncodingUTF8, // cut off in the middle of a rune
+	}
+	for s, expected := range encodings {
+		if enc := DetectEncoding([]byte(s)); enc != expected {
+			t.Errorf("Expected %s, got %s for %q", expected, enc, s)
+		}
+	}
+}
+
+func TestDetectBytes(t *testing.T) {
+	tests := []struct {
+		data string
+		m    Mode
+		enc  Encoding
+	}{
+		{"\xef\xbb\xbf#!/bin/sh\necho hi\n", Shell, EncodingUTF8BOM},
+		{"\xef\xbb\xbf<?xml version=\"1.0\"?>\n<a/>\n", XML, EncodingUTF8BOM},
+		{"\xff\xfe<\x00?\x00x\x00m\x00l\x00 \x00", XML, EncodingUTF16LE},
+		{"\xfe\xff\x00#\x00!\x00/\x00b\x00i\x00n\x00/\x00b\x00a\x00s\x00h\x00\n", Shell, EncodingUTF16BE},
+		{"\x00\x01\x02\x03\x04binary", Binary, EncodingBinary},
+	}
+	for _, test := range tests {
+		if m, enc := DetectBytes([]byte(test.data)); m != test.m || enc != test.enc {
+			t.Errorf("Expected %s and %s, got %s and %s for %q", Mode(test.m), test.enc, m, enc, test.data)
+		}
+	}
+	if m := DetectFile("main.go", []byte("\x00\x01\x02\x03\x04binary")); m != Binary {
+		t.Errorf("Expected Binary, got %s", m)
+	}
+	if m := DetectFile("test.docx", []byte("PK\x03\x04\x14\x00\x00\x00")); m != DOCX {
+		t.Errorf("Expected DOCX, got %s", m)
+	}
+}
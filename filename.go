@@ -12,329 +12,611 @@ func hasS(sl []string, s string) bool {
 	return slices.Contains(sl, s)
 }
 
+// filenameModes maps exact filenames to modes
+var filenameModes = map[string]Mode{
+	".gitignore":     Ignore,
+	".ignore":        Ignore,
+	"APKBUILD":       Shell,
+	"BUILD":          Bazel,
+	"CMakeLists.txt": CMake,
+	"COMMIT_EDITMSG": Git,
+	"Dockerfile":     Docker,
+	"GNUmakefile":    Make,
+	"MERGE_MSG":      Git,
+	"Modelfile":      Ollama,
+	"PKGBUILD":       Shell,
+	"SKILL.md":       Skill,
+	"WORKSPACE":      Bazel,
+	"dockerfile":     Docker,
+	"fstab":          FSTAB,
+	"justfile":       Just,
+	"modelfile":      Ollama,
+	"svn-commit.tmp": Subversion,
+	// Conventional files in uppercase, where README and the like are checked by DetectFile
+	"AUTHORS":      Text,
+	"CHANGELOG":    Markdown,
+	"CHANGES":      Markdown,
+	"CODEOWNERS":   CodeOwners,
+	"CONTRIBUTING": Markdown,
+	"CONTRIBUTORS": Text,
+	"COPYING":      Text,
+	"COPYRIGHT":    Text,
+	"HACKING":      Markdown,
+	"HISTORY":      Markdown,
+	"INSTALL":      Text,
+	"LICENCE":      Text,
+	"LICENSE":      Text,
+	"MAINTAINERS":  Text,
+	"NEWS":         Markdown,
+	"NOTICE":       Text,
+	"OWNERS":       Text,
+	"README":       Markdown,
+	"THANKS":       Text,
+	"TODO":         Markdown,
+	"VERSION":      Text,
+	// Shell startup files
+	".bash_aliases": Shell,
+	".bash_login":   Shell,
+	".bash_logout":  Shell,
+	".bash_profile": Shell,
+	".bashrc":       Shell,
+	".cshrc":        Shell,
+	".kshrc":        Shell,
+	".login":        Shell,
+	".logout":       Shell,
+	".mkshrc":       Shell,
+	".shrc":         Shell,
+	".tcshrc":       Shell,
+	".zlogin":       Shell,
+	".zlogout":      Shell,
+	".zprofile":     Shell,
+	".zshenv":       Shell,
+	".zshrc":        Shell,
+	"bash.bashrc":   Shell,
+	"bashrc":        Shell,
+	"zlogin":        Shell,
+	"zlogout":       Shell,
+	"zprofile":      Shell,
+	"zshenv":        Shell,
+	"zshrc":         Shell,
+	// Build and tool files that end with "file", but are not configuration files
+	"Appfile":       Ruby,
+	"Berksfile":     Ruby,
+	"Brewfile":      Ruby,
+	"Caddyfile":     Caddyfile,
+	"Capfile":       Ruby,
+	"Containerfile": Docker,
+	"Dangerfile":    Ruby,
+	"Earthfile":     Docker,
+	"Fastfile":      Ruby,
+	"Gemfile":       Ruby,
+	"Guardfile":     Ruby,
+	"Jenkinsfile":   Groovy,
+	"Pipfile":       TOML,
+	"Podfile":       Ruby,
+	"Procfile":      Procfile,
+	"Rakefile":      Ruby,
+	"Snakefile":     Python,
+	"Tiltfile":      Starlark,
+	"Vagrantfile":   Ruby,
+	// The most common configuration filenames that do not have an extension
+	"config":      Config,
+	"environment": Config,
+	"group":       Config,
+	"gshadow":     Config,
+	"hostname":    Config,
+	"hosts":       Config,
+	"issue":       Config,
+	"mirrorlist":  Config,
+	"passwd":      Config,
+	"shadow":      Config,
+	// Configuration files with a flavor, see DetectFlavor
+	".Xdefaults":  Config,
+	".Xresources": Config,
+	".htaccess":   Config,
+	"Xresources":  Config,
+	"crontab":     Config,
+	"ssh_config":  Config,
+	"sshd_config": Config,
+	"sudoers":     Config,
+	// Log files without the .log extension, see DetectLogFormat
+	"access_log": Log,
+	"dmesg":      Log,
+	"error_log":  Log,
+	"syslog":     Log,
+}
+
+// extensionModes maps filename extensions to modes
+var extensionModes = map[string]Mode{
+	".1":            Nroff,
+	".2":            Nroff,
+	".3":            Nroff,
+	".4":            Nroff,
+	".5":            Nroff,
+	".6":            Nroff,
+	".7":            Nroff,
+	".7z":           Archive,
+	".8":            Nroff, // not .9
+	".a68":          Algol68,
+	".abc":          ABC,
+	".abw":          Abiword,
+	".ada":          Ada,
+	".adb":          Ada,
+	".adoc":         ASCIIDoc,
+	".ads":          Ada,
+	".agda":         Agda,
+	".aidl":         AIDL,
+	".amber":        Amber,
+	".asm":          Assembly,
+	".atom":         XML,
+	".automount":    Config,
+	".bas":          Basic,
+	".bash":         Shell,
+	".bat":          Bat,
+	".bazel":        Bazel,
+	".bf":           Beef,
+	".blp":          Blueprint,
+	".bmp":          Image,
+	".bp":           Config,
+	".bts":          Battlestar,
+	".bzl":          Bazel,
+	".c":            C,
+	".c++":          Cpp,
+	".c3":           C3,
+	".cabal":        Haskell,
+	".caddyfile":    Caddyfile,
+	".cb":           COBOL,
+	".cbl":          COBOL,
+	".cby":          COBOL,
+	".cc":           Cpp,
+	".cfg":          Config,
+	".ck":           Chuck,
+	".cl":           Lisp,
+	".clj":          Clojure,
+	".cljs":         Clojure,
+	".clojure":      Clojure,
+	".cls":          Basic,
+	".cm":           StandardML, // Standard ML project file
+	".cmake":        CMake,
+	".cob":          COBOL,
+	".cobol":        COBOL,
+	".conf":         Config,
+	".cpp":          Cpp,
+	".cr":           Crystal,
+	".cs":           CS,
+	".csd":          CSound,
+	".csproj":       XML, // C# projects
+	".css":          CSS,
+	".csv":          CSV,
+	".ctl":          Basic,
+	".cxx":          Cpp,
+	".d":            D,
+	".dart":         Dart,
+	".desktop":      Config,
+	".dhall":        Dhall,
+	".diff":         Diff,
+	".dingo":        Dingo,
+	".diz":          Text,
+	".dll":          Executable,
+	".docx":         DOCX,
+	".dsp":          Faust,
+	".dylib":        Executable,
+	".el":           Lisp,
+	".elisp":        Lisp,
+	".elm":          Elm,
+	".emacs":        Lisp,
+	".eml":          Email,
+	".erl":          Erlang,
+	".ex":           Elixir,
+	".exe":          Executable,
+	".exs":          Elixir,
+	".f":            Fortran77,
+	".f90":          Fortran90,
+	".fish":         Shell,
+	".form":         Basic,
+	".frm":          Basic,
+	".fs":           FSharp,
+	".fsproj":       XML,
+	".fun":          StandardML,
+	".gambas":       Basic,
+	".gd":           GDScript,
+	".gemspec":      Ruby,
+	".gif":          Image,
+	".glade":        XML,
+	".gleam":        Gleam,
+	".glsl":         Shader,
+	".go":           Go,
+	".godot":        Config,
+	".gpr":          Ada,
+	".gradle":       Gradle,
+	".groovy":       Groovy,
+	".gsh":          Groovy,
+	".gt":           Garnet,
+	".gvy":          Groovy,
+	".gy":           Groovy,
+	".h":            Cpp, // TODO: Find a way to discover if a .h file is most likely to be C or C++
+	".h++":          Cpp,
+	".ha":           Hare,
+	".hal":          HIDL,
+	".hh":           Cpp,
+	".hlsl":         Shader,
+	".hpp":          Cpp,
+	".hs":           Haskell,
+	".htm":          HTML,
+	".html":         HTML,
+	".hts":          Haskell,
+	".http":         HTTP,
+	".hx":           Haxe,
+	".hxml":         Haxe,
+	".ico":          Image,
+	".ign":          JSON,
+	".import":       Config,
+	".inc":          Assembly, // TODO: Could be POV-Ray as well. Detect POV-Ray or Go-style Assembly from the contents.
+	".ini":          Ini,
+	".inko":         Inko,
+	".ino":          Arduino,
+	".install":      Shell,
+	".ipynb":        JSON,
+	".ivy":          Ivy,
+	".jakt":         Jakt,
+	".janet":        Janet,
+	".jl":           Julia,
+	".jar":          Archive,
+	".java":         Java,
+	".jpeg":         Image,
+	".jpg":          Image,
+	".js":           JavaScript,
+	".json":         JSON,
+	".json5":        JSON,
+	".jsonc":        JSON,
+	".jsonl":        JSON,
+	".jsonlines":    JSON,
+	".jsx":          JavaScript,
+	".just":         Just,
+	".justfile":     Just,
+	".kk":           Koka,
+	".ksh":          Shell,
+	".kt":           Kotlin,
+	".kts":          Kotlin,
+	".l":            Lisp,
+	".lisp":         Lisp,
+	".local":        Shell,
+	".log":          Log,
+	".lpr":          ObjectPascal,
+	".lsp":          Lisp,
+	".lua":          Lua,
+	".ly":           Lilypond,
+	".m":            ObjC,
+	".m4":           M4,
+	".mac":          Assembly,
+	".mak":          Make,
+	".markdown":     Markdown,
+	".md":           Markdown,
+	".mk":           Make,
+	".ml":           OCaml, // or Standard ML, if the file does not contain ";;"
+	".mod":          GoMod, // go.mod files
+	".module":       Basic,
+	".mojo":         Mojo,
+	".mount":        Config,
+	".ndjson":       JSON,
+	".nfo":          Text,
+	".nim":          Nim,
+	".nix":          Nix,
+	".nse":          Nmap,
+	".nu":           Nushell,
+	".nvim":         Vim,
+	".o":            Executable,
+	".odf":          LibreOffice,
+	".odg":          LibreOffice,
+	".odin":         Odin,
+	".odp":          LibreOffice,
+	".ods":          LibreOffice,
+	".odt":          LibreOffice,
+	".ok":           Oak,
+	".orc":          CSound,
+	".pas":          ObjectPascal,
+	".patch":        Diff,
+	".pdf":          PDF,
+	".perl":         Perl,
+	".php":          PHP,
+	".php3":         PHP,
+	".php4":         PHP,
+	".php5":         PHP,
+	".phtml":        PHP,
+	".pkl":          Pkl,
+	".pl":           Perl,
+	".plg":          Prolog,
+	".plist":        XML,
+	".png":          Image,
+	".podspec":      Ruby,
+	".pov":          POV,
+	".pp":           ObjectPascal,
+	".pptx":         PPTX,
+	".pro":          Prolog,
+	".profile":      Shell,
+	".prop":         Config,
+	".properties":   Config,
+	".props":        XML,
+	".proto":        Protobuf,
+	".py":           Python,
+	".r":            R,
+	".rake":         Ruby,
+	".rar":          Archive,
+	".razor":        XML,
+	".rb":           Ruby,
+	".rc":           Config,
+	".rej":          Diff, // .rej files contain the rejected hunks of a patch
+	".rkt":          Scheme,
+	".rs":           Rust,
+	".rss":          XML,
+	".rst":          ReStructured,
+	".rtf":          RTF,
+	".rule":         Config,
+	".rules":        Config,
+	".s":            Assembly,      // Go-style Assembly is detected from the contents
+	".sc":           SuperCollider, // TODO: Could be Scheme as well. Detect Scheme from the contents.
+	".scala":        Scala,
+	".scd":          SCDoc,
+	".scdoc":        SCDoc,
+	".sch":          Scheme,
+	".scm":          Scheme,
+	".sco":          CSound,
+	".scr":          Scheme,
+	".scrbl":        Scheme,
+	".service":      Config,
+	".sh":           Shell,
+	".sld":          Scheme,
+	".slice":        Config,
+	".sls":          Scheme,
+	".sml":          StandardML,
+	".so":           Executable,
+	".socket":       Config,
+	".spec":         Spec,
+	".sps":          Scheme,
+	".sps7":         Scheme,
+	".sql":          SQL,
+	".ss":           Scheme,
+	".star":         Starlark,
+	".starlark":     Starlark,
+	".svg":          XML,
+	".swift":        Swift,
+	".t":            Terra,
+	".tar":          Archive,
+	".target":       Config,
+	".targets":      XML,
+	".tcsh":         Shell,
+	".te":           PolicyLanguage,
+	".text":         Text,
+	".tf":           HCL,
+	".tfvars":       HCL,
+	".tgz":          Archive,
+	".tif":          Image,
+	".tiff":         Image,
+	".tim":          Tim,
+	".timer":        Config,
+	".tl":           Teal,
+	".toml":         TOML,
+	".tres":         Config,
+	".ts":           TypeScript,
+	".tsv":          CSV,
+	".tsx":          TypeScript,
+	".txt":          Text,
+	".v":            V,
+	".vbg":          Basic,
+	".vbp":          Basic,
+	".vbproj":       XML,
+	".vcxproj":      XML,
+	".vim":          Vim,
+	".vimrc":        Vim,
+	".wasm":         WebAssembly,
+	".webp":         Image,
+	".wg":           WordGrinder,
+	".wgsl":         WGSL,
+	".xaml":         XML,
+	".xlsx":         XLSX,
+	".xml":          XML,
+	".xsd":          XML,
+	".xsl":          XML,
+	".xslt":         XML,
+	".yaml":         YAML,
+	".yml":          YAML,
+	".zabw":         Abiword,
+	".zig":          Zig,
+	".zip":          Archive,
+	".zir":          Zig,
+	".zsh":          Shell,
+	"." + fireEmoji: Mojo,
+}
+
+// compoundExtensions maps extensions with more than one dot to modes, like TypeScript declarations
+// and compressed tarballs. They are looked up before the last extension, with the longest match first.
+// Suffixes that do not change the mode, like .spec.ts and .pb.go, are left to the last extension.
+var compoundExtensions = map[string]Mode{
+	".d.cts":   TypeScript,
+	".d.mts":   TypeScript,
+	".d.ts":    TypeScript,
+	".tar.bz2": Archive,
+	".tar.gz":  Archive,
+	".tar.lz4": Archive,
+	".tar.xz":  Archive,
+	".tar.zst": Archive,
+}
+
+// compoundExtension returns the longest extension with more than one dot that is
+// found in compoundExtensions, like ".d.ts" for index.d.ts, or an empty string
+func compoundExtension(baseFilename string) string {
+	lowerFilename := strings.ToLower(baseFilename)
+	// Start after the first character, so that the compound extension is not the whole filename
+	for i := 1; i < len(lowerFilename); i++ {
+		if lowerFilename[i] != '.' || !strings.Contains(lowerFilename[i+1:], ".") {
+			continue
+		}
+		if _, ok := compoundExtensions[lowerFilename[i:]]; ok {
+			return lowerFilename[i:]
+		}
+	}
+	return ""
+}
+
+// caseSensitiveExtensions are extensions where the case matters, and that are looked up before
+// extensionModes. All other extensions are looked up in lowercase, so that ie. SETUP.PY is Python.
+var caseSensitiveExtensions = map[string]Mode{
+	".C": Cpp,      // C++ on UNIX, while .c is C
+	".R": R,        // R, while .r may also be Rebol or Ratfor
+	".S": Assembly, // assembly that should be run through the C preprocessor
+}
+
+// filenameRule is a rule for filenames that can not be looked up in a map, like prefixes and suffixes
+type filenameRule struct {
+	match func(baseFilename, ext string) bool
+	mode  Mode
+}
+
+// filenameRules are checked in order, if neither the filename nor the extension is known
+var filenameRules = []filenameRule{
+	{func(baseFilename, ext string) bool { // ie. git-rebase-todo
+		return strings.HasPrefix(baseFilename, "git-") && ext == "" && strings.Count(baseFilename, "-") >= 2
+	}, Git},
+	{func(baseFilename, _ string) bool { // ie. Makefile.am and makefile.linux, but not MakeRequest.java
+		return strings.HasPrefix(baseFilename, "Make") || strings.HasPrefix(baseFilename, "makefile")
+	}, Make},
+	{func(baseFilename, ext string) bool { // ie. muttrc, and names ending with "file" that are not in filenameModes
+		return ext == "" && (strings.HasSuffix(baseFilename, "file") || strings.HasSuffix(baseFilename, "rc"))
+	}, Config},
+	{func(baseFilename, ext string) bool { // ie. .npmrc and .ssh_config, but not .bashrc, which is in filenameModes
+		return ext == baseFilename && (strings.HasSuffix(baseFilename, "rc") || strings.HasSuffix(baseFilename, "config"))
+	}, Config},
+}
+
+// earlyExtensions are looked up before the prefix rules, ie. .zshrc.conf is Config and mutt-x.yml is YAML
+var earlyExtensions = []string{".Mak", ".bash", ".bazel", ".bp", ".bzl", ".cfg", ".cmake", ".conf", ".dhall", ".fish", ".godot", ".import", ".install", ".just", ".justfile", ".ksh", ".local", ".mak", ".mk", ".nvim", ".pkl", ".profile", ".prop", ".properties", ".rc", ".rule", ".service", ".sh", ".socket", ".target", ".tcsh", ".tf", ".tfvars", ".toml", ".tres", ".vim", ".vimrc", ".yaml", ".yml", ".zsh"}
+
+// makeFragmentExtensions are extensions of files that are included by makefiles, like makefile.inc
+var makeFragmentExtensions = []string{".d", ".inc", ".mk", ".rules"}
+
+// prefixRules are checked in order before the other extensions, since the extension of these
+// temporary, startup and makefile include files does not say anything about the contents
+var prefixRules = []filenameRule{
+	{func(baseFilename, ext string) bool { // ie. makefile.inc and Makefile.d, but not Makefile.py
+		return (strings.HasPrefix(baseFilename, "Make") || strings.HasPrefix(baseFilename, "makefile")) && hasS(makeFragmentExtensions, strings.ToLower(ext))
+	}, Make},
+	{func(baseFilename, ext string) bool { // ie. /tmp/man.0asdfadf
+		return strings.HasPrefix(baseFilename, "man.") && len(ext) > 4
+	}, ManPage},
+	{func(baseFilename, _ string) bool { // ie. /tmp/mutt-hostname-0000-0000-00000000000000000
+		return strings.HasPrefix(baseFilename, "mutt-")
+	}, Email},
+	{func(baseFilename, ext string) bool { // ie. .bashrc.local and .zshrc.d
+		return ext != "" && ext != baseFilename && filenameModes[strings.TrimSuffix(baseFilename, ext)] == Shell
+	}, Shell},
+}
+
 // Detect looks at the filename and tries to guess what could be an appropriate editor mode.
+// The rules are tried in this order: path rules, exact filenames, compound extensions (like .d.ts),
+// prefixes of temporary files (like mutt-*), extensions, prefixes and suffixes (like Makefile.am),
+// and finally the same rules without backup or template suffixes. Use DetectFile to also look at the contents.
 func Detect(filename string) Mode {
-	// A list of the most common configuration filenames that does not have an extension
-	var (
-		configFilenames = []string{"config", "environment", "group", "gshadow", "hostname", "hosts", "issue", "mirrorlist", "passwd", "shadow"}
-		mode            Mode
-	)
-
 	baseFilename := filepath.Base(filename)
 	ext := filepath.Ext(baseFilename)
+	lowerExt := strings.ToLower(ext)
+
+	// Rules for paths, like .github/workflows/*.yml, come first, since the directory can decide the mode.
+	// Broad rules, like /etc/cron.d/*, do not apply to files with a known extension, like backup.sh.
+	_, knownExtension := extensionModes[lowerExt]
+	if _, ok := caseSensitiveExtensions[ext]; ok || compoundExtension(baseFilename) != "" {
+		knownExtension = true
+	}
+	if m, ok := DefaultPathRules.match(filename, knownExtension); ok {
+		return m
+	}
 
-	// Check if we should be in a particular mode for a particular type of file
-	// TODO: Create a hash map to look up many of the extensions
-	switch {
-	case baseFilename == "COMMIT_EDITMSG" ||
-		baseFilename == "MERGE_MSG" ||
-		(strings.HasPrefix(baseFilename, "git-") &&
-			!strings.Contains(baseFilename, ".") &&
-			strings.Count(baseFilename, "-") >= 2):
-		// Git mode
-		mode = Git
-	case baseFilename == "Dockerfile" || baseFilename == "dockerfile":
-		mode = Docker
-	case baseFilename == "Modelfile" || baseFilename == "modelfile":
-		mode = Ollama
-	case baseFilename == "svn-commit.tmp":
-		mode = Subversion
-	case baseFilename == "fstab":
-		mode = FSTAB
-	case baseFilename == "SKILL.md":
-		mode = Skill
-	case ext == ".vimrc" || ext == ".vim" || ext == ".nvim":
-		mode = Vim
-	case ext == ".mk" || ext == ".mak" || ext == ".Mak" || strings.HasPrefix(baseFilename, "Make") || strings.HasPrefix(baseFilename, "makefile") || baseFilename == "GNUmakefile":
-		// NOTE: This one MUST come before the ext == "" check below!
-		mode = Make
-	case ext == ".just" || ext == ".justfile" || baseFilename == "justfile":
-		// NOTE: This one MUST come before the ext == "" check below!
-		mode = Just
-	case ext == ".bzl" || ext == ".bazel" || baseFilename == "BUILD" || baseFilename == "WORKSPACE":
-		mode = Bazel
-	case ext == ".yml" || ext == ".yaml":
-		mode = YAML
-	case ext == ".toml":
-		mode = TOML
-	case ext == ".tf" || ext == ".tfvars":
-		mode = HCL
-	case ext == ".dhall":
-		mode = Dhall
-	case ext == ".pkl":
-		mode = Pkl
-	case strings.HasSuffix(filename, ".git/config") || ext == ".cfg" || ext == ".conf" || ext == ".service" || ext == ".target" || ext == ".socket" || ext == ".godot" || ext == ".import" || ext == ".tres" || ext == ".rc" || ext == ".prop" || ext == ".properties":
-		fallthrough
-	case ext == ".bp" || ext == ".rule" || (ext == "" && (strings.HasSuffix(baseFilename, "file") || strings.HasSuffix(baseFilename, "rc") || hasS(configFilenames, baseFilename))):
-		mode = Config
-	case ext == ".sh" || ext == ".fish" || ext == ".install" || ext == ".ksh" || ext == ".tcsh" || ext == ".bash" || ext == ".zsh" || ext == ".local" || ext == ".profile" || baseFilename == "PKGBUILD" || baseFilename == "APKBUILD" || (strings.HasPrefix(baseFilename, ".") && strings.Contains(baseFilename, "sh")): // This last part covers .bashrc, .zshrc etc
-		mode = Shell
-	case baseFilename == ".gitignore" || baseFilename == ".ignore":
-		mode = Ignore
-	case baseFilename == "CMakeLists.txt" || ext == ".cmake":
-		mode = CMake
-	case strings.HasPrefix(baseFilename, "man.") && len(ext) > 4: // ie.: /tmp/man.0asdfadf
-		mode = ManPage
-	case strings.HasPrefix(baseFilename, "mutt-"): // ie.: /tmp/mutt-hostname-0000-0000-00000000000000000
-		mode = Email
-	case strings.HasSuffix(baseFilename, "Log.txt"): // ie. MinecraftLog.txt
-		mode = Log
-	default:
-		switch ext {
-		case ".1", ".2", ".3", ".4", ".5", ".6", ".7", ".8": // not .9
-			mode = Nroff
-		case ".a68":
-			mode = Algol68
-		case ".adb", ".gpr", ".ads", ".ada":
-			mode = Ada
-		case ".adoc":
-			mode = ASCIIDoc
-		case ".scdoc", ".scd":
-			mode = SCDoc
-		case ".abc":
-			mode = ABC
-		case ".abw", ".zabw":
-			mode = Abiword
-		case ".aidl":
-			mode = AIDL
-		case ".agda":
-			mode = Agda
-		case ".amber":
-			mode = Amber
-		case ".bas", ".module", ".frm", ".cls", ".ctl", ".vbp", ".vbg", ".form", ".gambas":
-			mode = Basic
-		case ".bat":
-			mode = Bat
-		case ".bf":
-			mode = Beef
-		case ".blp":
-			mode = Blueprint
-		case ".bts":
-			mode = Battlestar
-		case ".c":
-			mode = C
-		case ".c3":
-			mode = C3
-		case ".cb", ".cbl", ".cob", ".cby", ".cobol":
-			mode = COBOL
-		case ".cm":
-			// Standard ML project file
-			mode = StandardML
-		case ".cpp", ".cc", ".c++", ".cxx", ".hh", ".hpp", ".h", ".h++": // C++ mode
-			// TODO: Find a way to discover is a .h file is most likely to be C or C++
-			mode = Cpp
-		case ".clj", ".clojure", ".cljs":
-			mode = Clojure
-		case ".cs": // C#
-			mode = CS
-		case ".csd", ".orc", ".sco":
-			mode = CSound
-		case ".css":
-			mode = CSS
-		case ".csv":
-			mode = CSV
-		case ".tsv":
-			mode = CSV
-		case ".csproj": // C# projects
-			mode = XML
-		case ".ck":
-			mode = Chuck
-		case ".cl", ".el", ".elisp", ".emacs", ".l", ".lisp", ".lsp":
-			mode = Lisp
-		case ".cr":
-			mode = Crystal
-		case ".d":
-			mode = D
-		case ".dart":
-			mode = Dart
-		case ".docx":
-			mode = DOCX
-		case ".dingo":
-			mode = Dingo
-		case ".patch", ".diff":
-			mode = Diff
-		case ".ex", ".exs":
-			mode = Elixir
-		case ".elm":
-			mode = Elm
-		case ".eml":
-			mode = Email
-		case ".erl":
-			mode = Erlang
-		case ".dsp":
-			mode = Faust
-		case ".f":
-			mode = Fortran77
-		case ".f90":
-			mode = Fortran90
-		case ".fs":
-			mode = FSharp
-		case ".gd":
-			mode = GDScript
-		case ".gt":
-			mode = Garnet
-		case ".go":
-			mode = Go
-		case ".gleam":
-			mode = Gleam
-		case ".glsl", ".hlsl":
-			mode = Shader
-		case ".gradle":
-			mode = Gradle
-		case ".ha":
-			mode = Hare
-		case ".hal":
-			mode = HIDL
-		case ".hs", ".hts", ".cabal":
-			mode = Haskell
-		case ".htm", ".html":
-			mode = HTML
-		case ".http":
-			mode = HTTP
-		case ".hx", ".hxml":
-			mode = Haxe
-		case ".ini":
-			mode = Ini
-		case ".ino":
-			mode = Arduino
-		case ".inko":
-			mode = Inko
-		case ".ivy":
-			mode = Ivy
-		case ".jakt":
-			mode = Jakt
-		case ".janet":
-			mode = Janet
-		case ".java":
-			mode = Java
-		case ".js", ".jsx":
-			mode = JavaScript
-		case ".ign", ".ipynb", ".json":
-			mode = JSON
-		case ".kk":
-			mode = Koka
-		case ".kt", ".kts":
-			mode = Kotlin
-		case ".log":
-			mode = Log
-		case ".lua":
-			mode = Lua
-		case ".ly":
-			mode = Lilypond
-		case ".odt", ".ods", ".odp", ".odg", ".odf":
-			mode = LibreOffice
-		case ".m":
-			mode = ObjC
-		case ".m4":
-			mode = M4
-		case ".md", ".markdown":
-			// Markdown mode
-			mode = Markdown
-		case ".ml":
-			mode = OCaml // or standard ML, if the file does not contain ";;"
-		case ".mod":
-			mode = GoMod // go.mod files
-		case ".nim":
-			mode = Nim
-		case ".nix":
-			mode = Nix
-		case ".nse":
-			mode = Nmap
-		case ".nu":
-			mode = Nushell
-		case ".odin":
-			mode = Odin
-		case ".ok":
-			mode = Oak
-		case ".pas", ".pp", ".lpr":
-			mode = ObjectPascal
-		case ".php", ".php3", ".php4", ".php5", ".phtml":
-			mode = PHP
-		case ".pl", ".perl":
-			mode = Perl
-		case ".plg", ".pro":
-			mode = Prolog
-		case ".proto":
-			mode = Protobuf
-		case ".py":
-			mode = Python
-		case ".pov":
-			mode = POV
-		case ".mojo", "." + fireEmoji:
-			mode = Mojo
-		case ".r":
-			mode = R
-		case ".rb":
-			mode = Ruby
-		case ".razor":
-			mode = XML
-		case ".rs":
-			mode = Rust
-		case ".rst":
-			mode = ReStructured // reStructuredText
-		case ".rtf":
-			mode = RTF
-		case ".S", ".asm", ".mac", ".s":
-			// Temporary set the mode to Assembly. Go-style Assembly will be detected from the contents.
-			mode = Assembly
-		case ".inc":
-			mode = Assembly // TODO: Could be POV-Ray as well. Detect POV-Ray or Go-style Assembly from the contents.
-		case ".sc":
-			mode = SuperCollider // TODO: Could be Scheme as well. Detect Scheme from the contents.
-		case ".scala":
-			mode = Scala
-		case ".star", ".starlark":
-			mode = Starlark
-		case ".rkt", ".sch", ".scm", ".scr", ".scrbl", ".sld", ".sls", ".sps", ".sps7", ".ss":
-			mode = Scheme
-		case ".swift":
-			mode = Swift
-		case ".fun", ".sml":
-			mode = StandardML
-		case ".spec":
-			mode = Spec
-		case ".sql":
-			mode = SQL
-		case ".t":
-			mode = Terra
-		case ".te":
-			mode = PolicyLanguage
-		case ".tim":
-			mode = Tim
-		case ".tl":
-			mode = Teal
-		case ".ts", ".tsx":
-			mode = TypeScript
-		case ".wg":
-			mode = WordGrinder
-		case ".wgsl":
-			mode = WGSL
-		case ".txt", ".text", ".nfo", ".diz":
-			mode = Text
-		case ".v":
-			mode = V
-		case ".xml":
-			mode = XML
-		case ".zig", ".zir":
-			mode = Zig
-		default:
-			mode = Blank
+	// Exact filenames
+	if m, ok := filenameModes[baseFilename]; ok {
+		return m
+	}
+
+	// Compound extensions, and names that end with more than the extension
+	if compound := compoundExtension(baseFilename); compound != "" {
+		return compoundExtensions[compound]
+	}
+	if strings.HasSuffix(baseFilename, "Log.txt") || isRotatedLog(baseFilename) { // ie. MinecraftLog.txt, app.log.1 or syslog.1
+		return Log
+	}
+	if !hasS(earlyExtensions, ext) {
+		if m := detectFromRules(prefixRules, baseFilename, ext); m != Blank {
+			return m
+		}
+	}
+
+	// Extensions
+	if m, ok := caseSensitiveExtensions[ext]; ok {
+		return m
+	}
+	if m, ok := extensionModes[lowerExt]; ok {
+		return m
+	}
+	if _, ok := compressionSuffixes[lowerExt]; ok {
+		// Look at the filename without the compression suffix, ie. app.log.gz
+		innerFilename, _ := StripCompression(filename)
+		if m := Detect(innerFilename); m != Blank {
+			return m
+		}
+		return Archive
+	}
+
+	// Prefixes and suffixes
+	if m := detectFromRules(filenameRules, baseFilename, ext); m != Blank {
+		return m
+	}
+
+	// Try again without any backup or template suffixes, ie. for nginx.conf.bak,
+	// unless the template language has its own mode, ie. ERB for index.html.erb
+	if inner, template := stripSuffixes(baseFilename); inner != baseFilename {
+		if m := template.Mode(); m != Blank {
+			return m
 		}
+		return Detect(filepath.Join(filepath.Dir(filename), inner))
 	}
 
-	// If the mode is not set, and there is no extensions
-	if mode == Blank && !strings.Contains(baseFilename, ".") {
+	// If there is no extension
+	if !strings.Contains(baseFilename, ".") {
 		if baseFilename == strings.ToUpper(baseFilename) {
-			// If the filename is all uppercase and no ".", use mode.Markdown
-			mode = Markdown
+			// If the filename is all uppercase and no ".", assume it is a text file, like PATENTS
+			return Text
 		} else if len(baseFilename) > 2 && baseFilename[2] == '-' {
 			// Could it be a rule-file, that starts with ie. "90-" ?
 			if _, err := strconv.Atoi(baseFilename[:2]); err == nil { // success
 				// Yes, assume this is a shell-like configuration file
-				mode = Config
+				return Config
 			}
 		}
 	}
 
-	return mode
+	return Blank
+}
+
+// detectFromRules returns the mode of the first rule that matches, or Blank
+func detectFromRules(rules []filenameRule, baseFilename, ext string) Mode {
+	for _, rule := range rules {
+		if rule.match(baseFilename, ext) {
+			return rule.mode
+		}
+	}
+	return Blank
+}
+
+// rotatedLogNames are the names of log files without the .log extension that are often rotated, like syslog.1
+var rotatedLogNames = []string{"access_log", "dmesg", "error_log", "messages", "syslog"}
+
+// isRotatedLog checks if the given filename is a rotated log file, like app.log.1, app.log.2024-01-02 or syslog.1
+func isRotatedLog(baseFilename string) bool {
+	if _, suffix, found := strings.Cut(baseFilename, ".log."); found {
+		return suffix != "" && strings.Trim(suffix, "0123456789.-_") == ""
+	}
+	for _, name := range rotatedLogNames {
+		if suffix, found := strings.CutPrefix(baseFilename, name); found && len(suffix) > 1 && strings.ContainsRune(".-_", rune(suffix[0])) {
+			return strings.Trim(suffix, "0123456789.-_") == "" && strings.Trim(suffix, ".-_") != ""
+		}
+	}
+	return false
 }
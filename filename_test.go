@@ -102,3 +102,446 @@ func TestDetect(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDetectBinaryFormats(t *testing.T) {
+	formats := map[string]Mode{
+		"report.pdf":    PDF,
+		"logo.png":      Image,
+		"photo.jpg":     Image,
+		"module.wasm":   WebAssembly,
+		"sheet.xlsx":    XLSX,
+		"slides.pptx":   PPTX,
+		"release.zip":   Archive,
+		"libfoo.so":     Executable,
+		"installer.exe": Executable,
+	}
+	for filename, expected := range formats {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
+
+// detectCorpus is a table of filenames and the modes that Detect should return for them
+var detectCorpus = []struct {
+	filename string
+	mode     Mode
+}{
+	{"main.go", Go},
+	{"go.mod", GoMod},
+	{"go.sum", Blank},
+	{"README.md", Markdown},
+	{"README", Markdown},
+	{"LICENSE", Text},
+	{"Makefile", Make},
+	{"makefile", Make},
+	{"GNUmakefile", Make},
+	{"Makefile.am", Make},
+	{"build.mk", Make},
+	{"rules.mak", Make},
+	{"rules.Mak", Make},
+	{"Dockerfile", Docker},
+	{"dockerfile", Docker},
+	{"Modelfile", Ollama},
+	{"docker-compose.yml", YAML},
+	{"config.yaml", YAML},
+	{"Cargo.toml", TOML},
+	{"main.tf", HCL},
+	{"vars.tfvars", HCL},
+	{"config.dhall", Dhall},
+	{"config.pkl", Pkl},
+	{"COMMIT_EDITMSG", Git},
+	{"MERGE_MSG", Git},
+	{"git-rebase-todo", Git},
+	{"svn-commit.tmp", Subversion},
+	{"fstab", FSTAB},
+	{"SKILL.md", Skill},
+	{".vimrc", Vim},
+	{"init.vim", Vim},
+	{"init.nvim", Vim},
+	{"justfile", Just},
+	{"build.just", Just},
+	{"BUILD", Bazel},
+	{"WORKSPACE", Bazel},
+	{"BUILD.bazel", Bazel},
+	{"defs.bzl", Bazel},
+	{"setup.cfg", Config},
+	{"nginx.conf", Config},
+	{"foo.service", Config},
+	{"foo.target", Config},
+	{"foo.socket", Config},
+	{"project.godot", Config},
+	{"icon.png.import", Config},
+	{"scene.tres", Config},
+	{"app.rc", Config},
+	{"build.prop", Config},
+	{"app.properties", Config},
+	{"Android.bp", Config},
+	{"99-udev.rule", Config},
+	{"Rakefile", Ruby},
+	{"Gemfile", Ruby},
+	{".npmrc", Config},
+	{"config", Config},
+	{"hosts", Config},
+	{"passwd", Config},
+	{"mirrorlist", Config},
+	{"test.sh", Shell},
+	{"config.fish", Shell},
+	{"foo.install", Shell},
+	{"x.ksh", Shell},
+	{"x.tcsh", Shell},
+	{"x.bash", Shell},
+	{"x.zsh", Shell},
+	{"rc.local", Shell},
+	{"foo.profile", Shell},
+	{"PKGBUILD", Shell},
+	{"APKBUILD", Shell},
+	{".bashrc", Shell},
+	{".zshrc", Shell},
+	{".bash_profile", Shell},
+	{".gitignore", Ignore},
+	{".ignore", Ignore},
+	{"CMakeLists.txt", CMake},
+	{"FindFoo.cmake", CMake},
+	{"/tmp/man.XXXXtweZrK", ManPage},
+	{"/tmp/mutt-host-1000-1234-5678", Email},
+	{"MinecraftLog.txt", Log},
+	{"ls.1", Nroff},
+	{"foo.8", Nroff},
+	{"foo.9", Blank},
+	{"x.a68", Algol68},
+	{"x.adb", Ada},
+	{"x.ads", Ada},
+	{"doc.adoc", ASCIIDoc},
+	{"x.scd", SCDoc},
+	{"tune.abc", ABC},
+	{"x.abw", Abiword},
+	{"x.zabw", Abiword},
+	{"I.aidl", AIDL},
+	{"x.agda", Agda},
+	{"x.amber", Amber},
+	{"x.bas", Basic},
+	{"x.bat", Bat},
+	{"x.bf", Beef},
+	{"x.blp", Blueprint},
+	{"x.bts", Battlestar},
+	{"main.c", C},
+	{"x.c3", C3},
+	{"x.cbl", COBOL},
+	{"x.cm", StandardML},
+	{"main.cpp", Cpp},
+	{"x.cc", Cpp},
+	{"x.h", Cpp},
+	{"x.hpp", Cpp},
+	{"core.clj", Clojure},
+	{"Program.cs", CS},
+	{"x.csd", CSound},
+	{"style.css", CSS},
+	{"data.csv", CSV},
+	{"data.tsv", CSV},
+	{"App.csproj", XML},
+	{"x.ck", Chuck},
+	{"init.el", Lisp},
+	{"x.lisp", Lisp},
+	{"x.cr", Crystal},
+	{"x.d", D},
+	{"main.dart", Dart},
+	{"x.docx", DOCX},
+	{"x.dingo", Dingo},
+	{"fix.patch", Diff},
+	{"fix.diff", Diff},
+	{"x.ex", Elixir},
+	{"x.exs", Elixir},
+	{"Main.elm", Elm},
+	{"x.eml", Email},
+	{"x.erl", Erlang},
+	{"x.dsp", Faust},
+	{"x.f", Fortran77},
+	{"x.f90", Fortran90},
+	{"x.fs", FSharp},
+	{"x.gd", GDScript},
+	{"x.gt", Garnet},
+	{"x.gleam", Gleam},
+	{"x.glsl", Shader},
+	{"x.hlsl", Shader},
+	{"build.gradle", Gradle},
+	{"x.ha", Hare},
+	{"x.hal", HIDL},
+	{"Main.hs", Haskell},
+	{"x.cabal", Haskell},
+	{"index.htm", HTML},
+	{"index.html", HTML},
+	{"api.http", HTTP},
+	{"x.hx", Haxe},
+	{"local.ini", Ini},
+	{"x.ino", Arduino},
+	{"x.inko", Inko},
+	{"x.ivy", Ivy},
+	{"x.jakt", Jakt},
+	{"x.janet", Janet},
+	{"x.jl", Julia},
+	{"Main.java", Java},
+	{"app.js", JavaScript},
+	{"app.jsx", JavaScript},
+	{"x.ign", JSON},
+	{"nb.ipynb", JSON},
+	{"package.json", JSON},
+	{"x.kk", Koka},
+	{"Main.kt", Kotlin},
+	{"build.gradle.kts", Kotlin},
+	{"app.log", Log},
+	{"init.lua", Lua},
+	{"x.ly", Lilypond},
+	{"x.odt", LibreOffice},
+	{"x.ods", LibreOffice},
+	{"x.m", ObjC},
+	{"x.m4", M4},
+	{"x.markdown", Markdown},
+	{"x.ml", OCaml},
+	{"x.nim", Nim},
+	{"default.nix", Nix},
+	{"x.nse", Nmap},
+	{"x.nu", Nushell},
+	{"x.odin", Odin},
+	{"x.ok", Oak},
+	{"x.pas", ObjectPascal},
+	{"x.pp", ObjectPascal},
+	{"index.php", PHP},
+	{"x.pl", Perl},
+	{"x.pro", Prolog},
+	{"x.proto", Protobuf},
+	{"setup.py", Python},
+	{"scene.pov", POV},
+	{"x.mojo", Mojo},
+	{"x.r", R},
+	{"app.rb", Ruby},
+	{"x.razor", XML},
+	{"main.rs", Rust},
+	{"index.rst", ReStructured},
+	{"x.rtf", RTF},
+	{"main.S", Assembly},
+	{"main.s", Assembly},
+	{"x.asm", Assembly},
+	{"x.inc", Assembly},
+	{"x.sc", SuperCollider},
+	{"x.scala", Scala},
+	{"x.star", Starlark},
+	{"x.scm", Scheme},
+	{"x.rkt", Scheme},
+	{"x.swift", Swift},
+	{"x.sml", StandardML},
+	{"foo.spec", Spec},
+	{"query.sql", SQL},
+	{"x.t", Terra},
+	{"x.te", PolicyLanguage},
+	{"x.tim", Tim},
+	{"x.tl", Teal},
+	{"app.ts", TypeScript},
+	{"App.tsx", TypeScript},
+	{"doc.wg", WordGrinder},
+	{"x.wgsl", WGSL},
+	{"notes.txt", Text},
+	{"x.nfo", Text},
+	{"x.v", V},
+	{"pom.xml", XML},
+	{"build.zig", Zig},
+	{"app.log.gz", Log},
+	{"x.tar.gz", Archive},
+	{"x.zip", Archive},
+	{"logo.png", Image},
+	{"x.pdf", PDF},
+	{"x.wasm", WebAssembly},
+	{"nginx.conf.bak", Config},
+	{"config.yml~", YAML},
+	{"#notes.md#", Markdown},
+	{"settings.json.in", JSON},
+	{"Dockerfile.template", Docker},
+	{"index.html.j2", HTML},
+	{"foo.py.rej", Diff},
+	{"90-libvirt-mydevice", Config},
+	{"a90-libvirt-mydevice", Blank},
+	{"TODO", Markdown},
+	{"unknown", Blank},
+	{"unknown.xyz", Blank},
+	{"/home/user/project/.git/config", Config},
+	{"/home/user/.kube/config", YAML},
+	{"/etc/nginx/sites-available/default", Config},
+	{"debian/rules", Make},
+	{"MakeRequest.java", Java},
+	{".ssh_config", Config},
+	{".shellcheckrc", Config},
+	{"FOO.C", Cpp},
+	{"README.MD", Markdown},
+	{"Main.JAVA", Java},
+	{"SETUP.PY", Python},
+	{"x.d.ts", TypeScript},
+	{"app.spec.ts", TypeScript},
+	{"foo.test.js", JavaScript},
+	{"view.blade.php", PHP},
+	{"show.html.erb", ERB},
+	{"x.module.css", CSS},
+	{"api.pb.go", Go},
+	// Prefix rules, with the same results as the switch that Detect used before
+	{"mutt-host-1000-1-2", Email},
+	{"mutt-host-1000-1-2.txt", Email},
+	{"mutt-host.example.com-1000-1-2", Email},
+	{"mutt-x.yml", YAML},
+	{"man.0asdfadf", ManPage},
+	{"man.x.html", ManPage},
+	{".bashrc.d", Shell},
+	{".bashrc.local", Shell},
+	{".zshrc.bak", Shell},
+	{".zshrc.conf", Config},
+	{"a.bashrc.d", D},
+	{"Make.vim", Vim},
+	{"Makefile.vim", Vim},
+	{"MinecraftLog.txt", Log},
+}
+
+func TestDetectCorpus(t *testing.T) {
+	for _, tc := range detectCorpus {
+		if m := Detect(tc.filename); m != tc.mode {
+			t.Errorf("Expected %s for %s, got %s", Mode(tc.mode), tc.filename, m)
+		}
+	}
+}
+
+func BenchmarkDetect(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, tc := range detectCorpus {
+			Detect(tc.filename)
+		}
+	}
+}
+
+func TestDetectExtensionCase(t *testing.T) {
+	cases := map[string]Mode{
+		"main.C":      Cpp,
+		"main.c":      C,
+		"start.S":     Assembly,
+		"start.s":     Assembly,
+		"plot.R":      R,
+		"plot.r":      R,
+		"NOTES.TXT":   Text,
+		"Index.Html":  HTML,
+		"rules.Mak":   Make,
+		"APP.LOG.GZ":  Log,
+		"Script.Bash": Shell,
+	}
+	for filename, expected := range cases {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
+
+func TestDetectCompoundExtensions(t *testing.T) {
+	cases := map[string]Mode{
+		"index.d.ts":        TypeScript,
+		"index.d.mts":       TypeScript,
+		"Button.test.js":    JavaScript,
+		"show.html.erb":     ERB,
+		"Show.HTML.ERB":     ERB,
+		"styles.module.css": CSS,
+		"api.pb.go":         Go,
+		"release.tar.gz":    Archive,
+		"app.log.gz":        Log,
+		"my.app.go":         Go,
+		".d.ts":             TypeScript,
+	}
+	for filename, expected := range cases {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+	if compoundExtension("index.d.ts") != ".d.ts" || compoundExtension("main.go") != "" || compoundExtension("app.spec.ts") != "" {
+		t.Fail()
+	}
+}
+
+func TestDetectToolFiles(t *testing.T) {
+	cases := map[string]Mode{
+		"Vagrantfile":   Ruby,
+		"Podfile":       Ruby,
+		"Brewfile":      Ruby,
+		"Jenkinsfile":   Groovy,
+		"build.groovy":  Groovy,
+		"Tiltfile":      Starlark,
+		"Snakefile":     Python,
+		"Caddyfile":     Caddyfile,
+		"Earthfile":     Docker,
+		"Containerfile": Docker,
+		"Procfile":      Procfile,
+		"Pipfile":       TOML,
+		"Someotherfile": Config,
+	}
+	for filename, expected := range cases {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
+
+// TestDetectCollisions checks that prefix and suffix rules do not catch files with a known extension
+func TestDetectCollisions(t *testing.T) {
+	cases := []struct {
+		filename string
+		mode     Mode
+	}{
+		{"MakeRequest.java", Java},
+		{"MakeTable.cs", CS},
+		{"Makefile.py", Python},
+		{"makefile_test.go", Go},
+		{"Makefile", Make},
+		{"Makefile.am", Make},
+		{"Makefile.inc", Make},
+		{"makefile.inc", Make},
+		{"makefile.d", Make},
+		{"Makefile.D", Make},
+		{"Makefile.rules", Make},
+		{"makefile.rules", Make},
+		{"/etc/udev/rules.d/99-x.rules", Config},
+		{"makefile.linux", Make},
+		{".ssh_config", Config},
+		{".shellcheckrc", Config},
+		{".bashrc", Shell},
+		{".bash_profile", Shell},
+		{".zshrc", Shell},
+		{"/etc/zsh/zshrc", Shell},
+		{"git-hooks.md", Markdown},
+		{"git-rebase-todo", Git},
+		{"MinecraftLog.txt", Log},
+		{"mutt-host-1000-1-2", Email},
+		{"mutt-host-1000-1-2.txt", Email},
+		{".bashrc.d", Shell},
+		{".zshrc.conf", Config},
+		// Changed on purpose, hidden files that only contain "sh" are no longer Shell
+		{".zsh.txt", Text},
+		{".ssh.d", D},
+		{".ssh", Blank},
+	}
+	for _, tc := range cases {
+		if m := Detect(tc.filename); m != tc.mode {
+			t.Errorf("Expected %s for %s, got %s", Mode(tc.mode), tc.filename, m)
+		}
+	}
+}
+
+func TestDetectUppercase(t *testing.T) {
+	cases := map[string]Mode{
+		"LICENSE":            Text,
+		"COPYING":            Text,
+		"AUTHORS":            Text,
+		"VERSION":            Text,
+		".github/CODEOWNERS": CodeOwners,
+		"PATENTS":            Text,
+		"README":             Markdown,
+		"CHANGELOG":          Markdown,
+		"PKGBUILD":           Shell,
+	}
+	for filename, expected := range cases {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
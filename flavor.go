@@ -0,0 +1,689 @@
+package mode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Flavor is a more specific kind of file within a Mode, like a GitHub Actions workflow within YAML.
+// Tools can use it for schema-aware completion and validation.
+type Flavor int
+
+const (
+	FlavorNone             Flavor = iota // no specific flavor
+	FlavorGitHubWorkflow                 // GitHub Actions workflows, in .github/workflows
+	FlavorGitLabCI                       // GitLab CI configuration, .gitlab-ci.yml
+	FlavorCompose                        // Docker Compose files, like compose.yaml
+	FlavorKubernetes                     // Kubernetes manifests, with apiVersion and kind
+	FlavorHelmChart                      // Helm chart metadata, Chart.yaml
+	FlavorHelmTemplate                   // Helm templates, in the templates directory of a chart
+	FlavorAnsible                        // Ansible playbooks
+	FlavorAnsibleTasks                   // Ansible task files, like roles/*/tasks/main.yml
+	FlavorOpenAPI30                      // OpenAPI 3.0 documents
+	FlavorOpenAPI31                      // OpenAPI 3.1 documents
+	FlavorSwagger                        // Swagger 2.0 documents
+	FlavorJSONC                          // JSON with comments and trailing commas, like tsconfig.json
+	FlavorJSON5                          // JSON5, with unquoted keys, single quotes and more
+	FlavorJSONLines                      // JSON Lines and NDJSON, with one value per line
+	FlavorNotebook                       // Jupyter notebooks
+	FlavorSVG                            // SVG images
+	FlavorXHTML                          // XHTML documents
+	FlavorMavenPOM                       // Maven project files, pom.xml
+	FlavorMSBuild                        // MSBuild projects, like .csproj files
+	FlavorAndroidManifest                // Android manifests, AndroidManifest.xml
+	FlavorPlist                          // Apple property lists
+	FlavorXSLT                           // XSLT stylesheets
+	FlavorXSD                            // XML Schema definitions
+	FlavorRSS                            // RSS feeds
+	FlavorAtom                           // Atom feeds
+	FlavorXAML                           // XAML user interfaces
+	FlavorGtkBuilder                     // GTK user interfaces, like Glade files
+	FlavorSystemd                        // systemd units, like .service files
+	FlavorNginx                          // nginx configuration
+	FlavorApache                         // Apache httpd configuration, including .htaccess
+	FlavorSSHConfig                      // OpenSSH client configuration, ssh_config and ~/.ssh/config
+	FlavorSSHDConfig                     // OpenSSH server configuration, sshd_config
+	FlavorSudoers                        // sudoers files
+	FlavorCrontab                        // crontab files
+	FlavorUdevRules                      // udev rules
+	FlavorDesktopEntry                   // desktop entries, .desktop files
+	FlavorXresources                     // X resources, like .Xresources
+	FlavorTmux                           // tmux configuration, .tmux.conf
+	FlavorI3                             // i3 and sway configuration
+	FlavorPacman                         // pacman configuration, pacman.conf
+	FlavorAndroidBlueprint               // Android build files, Android.bp
+	lastFlavor                           // lastFlavor is not a flavor, it is used for iterating over all flavors
+)
+
+// String returns a short string representing the given flavor
+func (f Flavor) String() string {
+	switch f {
+	case FlavorNone:
+		return "none"
+	case FlavorGitHubWorkflow:
+		return "GitHub Actions workflow"
+	case FlavorGitLabCI:
+		return "GitLab CI"
+	case FlavorCompose:
+		return "Docker Compose"
+	case FlavorKubernetes:
+		return "Kubernetes"
+	case FlavorHelmChart:
+		return "Helm chart"
+	case FlavorHelmTemplate:
+		return "Helm template"
+	case FlavorAnsible:
+		return "Ansible playbook"
+	case FlavorAnsibleTasks:
+		return "Ansible tasks"
+	case FlavorOpenAPI30:
+		return "OpenAPI 3.0"
+	case FlavorOpenAPI31:
+		return "OpenAPI 3.1"
+	case FlavorSwagger:
+		return "Swagger 2.0"
+	case FlavorJSONC:
+		return "JSON with comments"
+	case FlavorJSON5:
+		return "JSON5"
+	case FlavorJSONLines:
+		return "JSON Lines"
+	case FlavorNotebook:
+		return "Jupyter notebook"
+	case FlavorSVG:
+		return "SVG"
+	case FlavorXHTML:
+		return "XHTML"
+	case FlavorMavenPOM:
+		return "Maven POM"
+	case FlavorMSBuild:
+		return "MSBuild"
+	case FlavorAndroidManifest:
+		return "Android manifest"
+	case FlavorPlist:
+		return "property list"
+	case FlavorXSLT:
+		return "XSLT"
+	case FlavorXSD:
+		return "XML Schema"
+	case FlavorRSS:
+		return "RSS"
+	case FlavorAtom:
+		return "Atom"
+	case FlavorXAML:
+		return "XAML"
+	case FlavorGtkBuilder:
+		return "GTK UI"
+	case FlavorSystemd:
+		return "systemd unit"
+	case FlavorNginx:
+		return "nginx"
+	case FlavorApache:
+		return "Apache"
+	case FlavorSSHConfig:
+		return "ssh_config"
+	case FlavorSSHDConfig:
+		return "sshd_config"
+	case FlavorSudoers:
+		return "sudoers"
+	case FlavorCrontab:
+		return "crontab"
+	case FlavorUdevRules:
+		return "udev rules"
+	case FlavorDesktopEntry:
+		return "desktop entry"
+	case FlavorXresources:
+		return "X resources"
+	case FlavorTmux:
+		return "tmux"
+	case FlavorI3:
+		return "i3"
+	case FlavorPacman:
+		return "pacman"
+	case FlavorAndroidBlueprint:
+		return "Android Blueprint"
+	default:
+		return "?"
+	}
+}
+
+// Mode returns the Mode that the given flavor is a flavor of
+func (f Flavor) Mode() Mode {
+	switch f {
+	case FlavorNone:
+		return Blank
+	case FlavorJSONC, FlavorJSON5, FlavorJSONLines, FlavorNotebook:
+		return JSON
+	case FlavorSVG, FlavorXHTML, FlavorMavenPOM, FlavorMSBuild, FlavorAndroidManifest, FlavorPlist, FlavorXSLT, FlavorXSD, FlavorRSS, FlavorAtom, FlavorXAML, FlavorGtkBuilder:
+		return XML
+	case FlavorSystemd, FlavorNginx, FlavorApache, FlavorSSHConfig, FlavorSSHDConfig, FlavorSudoers, FlavorCrontab, FlavorUdevRules, FlavorDesktopEntry, FlavorXresources, FlavorTmux, FlavorI3, FlavorPacman, FlavorAndroidBlueprint:
+		return Config
+	case FlavorGitHubWorkflow, FlavorGitLabCI, FlavorCompose, FlavorKubernetes, FlavorHelmChart, FlavorHelmTemplate, FlavorAnsible, FlavorAnsibleTasks, FlavorOpenAPI30, FlavorOpenAPI31, FlavorSwagger:
+		return YAML
+	default:
+		return Blank
+	}
+}
+
+// Schema returns an identifier for the JSON Schema of the given flavor, which is usually a URL.
+// Kubernetes manifests give "kubernetes", which is what yaml-language-server uses for the built-in schemas.
+// For XML flavors, the URL of the XML Schema is returned.
+// Returns an empty string if there is no schema for the flavor.
+func (f Flavor) Schema() string {
+	switch f {
+	case FlavorGitHubWorkflow:
+		return "https://json.schemastore.org/github-workflow.json"
+	case FlavorGitLabCI:
+		return "https://gitlab.com/gitlab-org/gitlab/-/raw/master/app/assets/javascripts/editor/schema/ci.json"
+	case FlavorCompose:
+		return "https://raw.githubusercontent.com/compose-spec/compose-spec/master/schema/compose-spec.json"
+	case FlavorKubernetes:
+		return "kubernetes"
+	case FlavorHelmChart:
+		return "https://json.schemastore.org/chart.json"
+	case FlavorAnsible:
+		return "https://raw.githubusercontent.com/ansible/ansible-lint/main/src/ansiblelint/schemas/ansible.json#/$defs/playbook"
+	case FlavorAnsibleTasks:
+		return "https://raw.githubusercontent.com/ansible/ansible-lint/main/src/ansiblelint/schemas/ansible.json#/$defs/tasks"
+	case FlavorOpenAPI30:
+		return "https://spec.openapis.org/oas/3.0/schema/2021-09-28"
+	case FlavorOpenAPI31:
+		return "https://spec.openapis.org/oas/3.1/schema/2022-10-07"
+	case FlavorSwagger:
+		return "https://json.schemastore.org/swagger-2.0.json"
+	case FlavorNotebook:
+		return "https://raw.githubusercontent.com/jupyter/nbformat/main/nbformat/v4/nbformat.v4.schema.json"
+	case FlavorMavenPOM:
+		return "https://maven.apache.org/xsd/maven-4.0.0.xsd"
+	case FlavorXSLT:
+		return "https://www.w3.org/2007/schema-for-xslt20.xsd"
+	case FlavorXSD:
+		return "https://www.w3.org/2001/XMLSchema.xsd"
+	case FlavorSVG:
+		return "https://www.w3.org/TR/SVG11/svg.xsd"
+	}
+	return ""
+}
+
+// DetectFlavor looks at the filename and, if given, the start of the contents, and tries to find a more
+// specific Flavor of the Mode of the file. Returns FlavorNone if no specific flavor is found.
+func DetectFlavor(name string, data []byte) Flavor {
+	m := Detect(name)
+	if m == Blank && len(data) > 0 {
+		m = SimpleDetectBytes(data)
+	}
+	switch m {
+	case YAML:
+		return detectYAMLFlavor(name, data)
+	case JSON:
+		return detectJSONFlavor(name, data)
+	case XML:
+		return detectXMLFlavor(name, data)
+	case Config:
+		return detectConfigFlavor(name, data)
+	}
+	return FlavorNone
+}
+
+// detectYAMLFlavor tries to find the flavor of the given YAML file, first by the path and then by the contents
+func detectYAMLFlavor(name string, data []byte) Flavor {
+	name = filepath.ToSlash(name)
+	baseFilename := path.Base(name)
+	dirs := strings.Split(path.Dir(name), "/")
+	parentDir := dirs[len(dirs)-1]
+	switch {
+	case parentDir == "workflows" && len(dirs) > 1 && dirs[len(dirs)-2] == ".github":
+		return FlavorGitHubWorkflow
+	case baseFilename == ".gitlab-ci.yml" || strings.HasSuffix(baseFilename, ".gitlab-ci.yml"):
+		return FlavorGitLabCI
+	case isComposeFilename(baseFilename):
+		return FlavorCompose
+	case baseFilename == "Chart.yaml":
+		return FlavorHelmChart
+	case parentDir == "templates" && bytes.Contains(data, []byte("{{")):
+		return FlavorHelmTemplate
+	case parentDir == "tasks" || parentDir == "handlers":
+		if len(dirs) > 2 && dirs[len(dirs)-3] == "roles" {
+			return FlavorAnsibleTasks
+		}
+	}
+	if len(data) == 0 {
+		return FlavorNone
+	}
+	if len(data) > 64*1024 {
+		data = data[:64*1024]
+	}
+	keys := yamlTopLevelKeys(data)
+	switch openapi := strings.Trim(keys["openapi"], `"'`); {
+	case strings.HasPrefix(openapi, "3.0"):
+		return FlavorOpenAPI30
+	case strings.HasPrefix(openapi, "3."):
+		return FlavorOpenAPI31
+	}
+	switch {
+	case strings.Trim(keys["swagger"], `"'`) == "2.0":
+		return FlavorSwagger
+	case hasKeys(keys, "apiVersion", "kind"):
+		return FlavorKubernetes
+	case hasKeys(keys, "on", "jobs"):
+		return FlavorGitHubWorkflow
+	case isAnsiblePlaybook(data):
+		return FlavorAnsible
+	case hasKeys(keys, "services") && (len(keys) == 1 || hasKeys(keys, "volumes") || hasKeys(keys, "networks") || hasKeys(keys, "version")):
+		return FlavorCompose
+	}
+	return FlavorNone
+}
+
+// isComposeFilename checks if the given filename is a Docker Compose file, like docker-compose.override.yml
+func isComposeFilename(baseFilename string) bool {
+	for _, prefix := range []string{"docker-compose.", "compose."} {
+		if strings.HasPrefix(baseFilename, prefix) && (strings.HasSuffix(baseFilename, ".yml") || strings.HasSuffix(baseFilename, ".yaml")) {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlTopLevelKeys returns the keys that are not indented in the given YAML data, with the value on the same
+// line, if any. Keys from all documents in the data are returned.
+func yamlTopLevelKeys(data []byte) map[string]string {
+	keys := make(map[string]string)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 || line[0] == ' ' || line[0] == '\t' || line[0] == '#' || line[0] == '-' {
+			continue
+		}
+		key, value, found := bytes.Cut(line, []byte(":"))
+		if !found || (bytes.ContainsAny(key, " \t") && !bytes.HasPrefix(key, []byte(`"`))) {
+			continue
+		}
+		if i := bytes.Index(value, []byte(" #")); i >= 0 {
+			value = value[:i]
+		}
+		keys[strings.Trim(string(key), `"'`)] = string(bytes.TrimSpace(value))
+	}
+	return keys
+}
+
+// hasKeys checks if all the given keys are in the map
+func hasKeys(keys map[string]string, names ...string) bool {
+	for _, name := range names {
+		if _, ok := keys[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isAnsiblePlaybook checks if the given YAML data is a list of plays, where the plays have hosts
+// or import other playbooks
+func isAnsiblePlaybook(data []byte) bool {
+	inList := false
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if bytes.HasPrefix(line, []byte("- ")) {
+			inList = true
+		} else if len(line) > 0 && line[0] != ' ' && line[0] != '#' && !bytes.Equal(line, []byte("---")) {
+			inList = false
+		}
+		if !inList {
+			continue
+		}
+		for _, key := range []string{"hosts:", "import_playbook:", "ansible.builtin.import_playbook:"} {
+			if bytes.HasPrefix(line, []byte("- "+key)) || bytes.HasPrefix(line, []byte("  "+key)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsoncFilenames are JSON files that are read by tools that allow comments and trailing commas
+var jsoncFilenames = []string{".babelrc.json", ".devcontainer.json", ".eslintrc.json", "api-extractor.json", "devcontainer.json", "jsconfig.json", "tsconfig.json", "tslint.json"}
+
+// detectJSONFlavor tries to find the flavor of the given JSON file, first by the filename and then by the contents
+func detectJSONFlavor(name string, data []byte) Flavor {
+	name = filepath.ToSlash(name)
+	baseFilename := path.Base(name)
+	switch strings.ToLower(path.Ext(baseFilename)) {
+	case ".ipynb":
+		return FlavorNotebook
+	case ".jsonc":
+		return FlavorJSONC
+	case ".json5":
+		return FlavorJSON5
+	case ".jsonl", ".jsonlines", ".ndjson":
+		return FlavorJSONLines
+	}
+	if hasS(jsoncFilenames, baseFilename) || (strings.HasPrefix(baseFilename, "tsconfig.") && strings.HasSuffix(baseFilename, ".json")) || path.Base(path.Dir(name)) == ".vscode" {
+		return FlavorJSONC
+	}
+	if len(data) == 0 {
+		return FlavorNone
+	}
+	if len(data) > 64*1024 {
+		data = data[:64*1024]
+	}
+	if isJSONLines(data) {
+		return FlavorJSONLines
+	}
+	comments, trailingCommas, json5 := scanJSON(data)
+	switch {
+	case json5:
+		return FlavorJSON5
+	case comments || trailingCommas:
+		return FlavorJSONC
+	}
+	return FlavorNone
+}
+
+// isJSONLines checks if the given data has at least two lines, where each line is a JSON object or array
+func isJSONLines(data []byte) bool {
+	count := 0
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !(line[0] == '{' && line[len(line)-1] == '}') && !(line[0] == '[' && line[len(line)-1] == ']') {
+			return false
+		}
+		count++
+	}
+	return count > 1
+}
+
+// scanJSON looks through the given JSON data, outside of strings, for comments, trailing commas
+// and the JSON5 extensions: single quoted strings, unquoted keys, hexadecimal numbers, Infinity and NaN
+func scanJSON(data []byte) (comments, trailingCommas, json5 bool) {
+	afterComma := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"' || c == '\'':
+			if c == '\'' {
+				json5 = true
+			}
+			// Skip the string
+			for i++; i < len(data) && data[i] != c; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			comments = true
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			comments = true
+			if end := bytes.Index(data[i+2:], []byte("*/")); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(data)
+			}
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case (c == '}' || c == ']') && afterComma:
+			trailingCommas = true
+		case c == '0' && i+1 < len(data) && (data[i+1] == 'x' || data[i+1] == 'X'):
+			json5 = true
+		case c >= '0' && c <= '9' || c == '-':
+			// Skip the number, including any exponent
+			for i+1 < len(data) && bytes.IndexByte([]byte("0123456789.eE+-"), data[i+1]) >= 0 {
+				i++
+			}
+		case c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i+1 < len(data) && (data[i+1] == '_' || data[i+1] == '$' || data[i+1] >= 'a' && data[i+1] <= 'z' || data[i+1] >= 'A' && data[i+1] <= 'Z' || data[i+1] >= '0' && data[i+1] <= '9') {
+				i++
+			}
+			switch string(data[start : i+1]) {
+			case "true", "false", "null":
+			default:
+				json5 = true
+			}
+		}
+		afterComma = c == ','
+	}
+	return comments, trailingCommas, json5
+}
+
+// xmlExtensionFlavors maps filename extensions to XML flavors
+var xmlExtensionFlavors = map[string]Flavor{
+	".atom":    FlavorAtom,
+	".csproj":  FlavorMSBuild,
+	".fsproj":  FlavorMSBuild,
+	".glade":   FlavorGtkBuilder,
+	".plist":   FlavorPlist,
+	".props":   FlavorMSBuild,
+	".rss":     FlavorRSS,
+	".svg":     FlavorSVG,
+	".targets": FlavorMSBuild,
+	".vbproj":  FlavorMSBuild,
+	".vcxproj": FlavorMSBuild,
+	".xaml":    FlavorXAML,
+	".xsd":     FlavorXSD,
+	".xsl":     FlavorXSLT,
+	".xslt":    FlavorXSLT,
+}
+
+// xmlNamespaceFlavors maps XML namespaces of root elements to XML flavors
+var xmlNamespaceFlavors = map[string]Flavor{
+	"http://maven.apache.org/POM/4.0.0":                         FlavorMavenPOM,
+	"http://schemas.microsoft.com/developer/msbuild/2003":       FlavorMSBuild,
+	"http://schemas.microsoft.com/winfx/2006/xaml/presentation": FlavorXAML,
+	"http://www.w3.org/1999/XSL/Transform":                      FlavorXSLT,
+	"http://www.w3.org/1999/xhtml":                              FlavorXHTML,
+	"http://www.w3.org/2000/svg":                                FlavorSVG,
+	"http://www.w3.org/2001/XMLSchema":                          FlavorXSD,
+	"http://www.w3.org/2005/Atom":                               FlavorAtom,
+	"https://github.com/avaloniaui":                             FlavorXAML,
+	"http://schemas.microsoft.com/netfx/2009/xaml/presentation": FlavorXAML,
+	"http://schemas.microsoft.com/dotnet/2021/maui":             FlavorXAML,
+}
+
+// xmlRootFlavors maps the names of root elements without a namespace to XML flavors
+var xmlRootFlavors = map[string]Flavor{
+	"Project":         FlavorMSBuild,
+	"glade-interface": FlavorGtkBuilder,
+	"plist":           FlavorPlist,
+	"rss":             FlavorRSS,
+	"svg":             FlavorSVG,
+}
+
+// detectXMLFlavor tries to find the flavor of the given XML file, first by the filename
+// and then by the namespace and name of the root element
+func detectXMLFlavor(name string, data []byte) Flavor {
+	baseFilename := path.Base(filepath.ToSlash(name))
+	switch baseFilename {
+	case "pom.xml":
+		return FlavorMavenPOM
+	case "AndroidManifest.xml":
+		return FlavorAndroidManifest
+	}
+	if f, ok := xmlExtensionFlavors[strings.ToLower(path.Ext(baseFilename))]; ok {
+		return f
+	}
+	if bytes.HasPrefix(data, []byte("bplist00")) { // binary property lists
+		return FlavorPlist
+	}
+	if len(data) > 64*1024 {
+		data = data[:64*1024]
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return FlavorNone
+		}
+		switch v := t.(type) {
+		case xml.Directive:
+			if bytes.HasPrefix(v, []byte("DOCTYPE plist")) {
+				return FlavorPlist
+			}
+		case xml.StartElement:
+			if f, ok := xmlNamespaceFlavors[v.Name.Space]; ok {
+				return f
+			}
+			for _, attr := range v.Attr {
+				// Android manifests have the android namespace, but not as the default namespace
+				if v.Name.Local == "manifest" && attr.Name.Space == "xmlns" && attr.Value == "http://schemas.android.com/apk/res/android" {
+					return FlavorAndroidManifest
+				}
+			}
+			if v.Name.Space == "" && v.Name.Local == "interface" {
+				// <interface> is also used by D-Bus introspection data and others, so look for GtkBuilder elements
+				if bytes.Contains(data, []byte("<object class=")) || bytes.Contains(data, []byte("<requires lib=\"gtk")) {
+					return FlavorGtkBuilder
+				}
+				return FlavorNone
+			}
+			if v.Name.Space == "" {
+				return xmlRootFlavors[v.Name.Local]
+			}
+			return FlavorNone
+		}
+	}
+}
+
+// systemdExtensions are the filename extensions of systemd units
+var systemdExtensions = []string{".automount", ".mount", ".service", ".slice", ".socket", ".target", ".timer"}
+
+// detectConfigFlavor tries to find the flavor of the given configuration file,
+// first by the path and then by the contents
+func detectConfigFlavor(name string, data []byte) Flavor {
+	name = filepath.ToSlash(name)
+	baseFilename := path.Base(name)
+	ext := strings.ToLower(path.Ext(baseFilename))
+	dirs := strings.Split(path.Dir(name), "/")
+	parentDir := dirs[len(dirs)-1]
+	switch {
+	case baseFilename == "Android.bp" || ext == ".bp":
+		return FlavorAndroidBlueprint
+	case hasS(systemdExtensions, ext) || (ext == ".conf" && strings.HasSuffix(parentDir, ".d") && hasS(systemdExtensions, path.Ext(strings.TrimSuffix(parentDir, ".d")))):
+		// Units, and drop-in files like foo.service.d/override.conf
+		return FlavorSystemd
+	case baseFilename == "sudoers" || parentDir == "sudoers.d":
+		return FlavorSudoers
+	case baseFilename == "crontab" || strings.HasPrefix(baseFilename, "crontab.") || parentDir == "cron.d" || (parentDir == "crontabs" || parentDir == "cron") && hasS(dirs, "spool"):
+		return FlavorCrontab
+	case baseFilename == "ssh_config" || parentDir == "ssh_config.d" || (baseFilename == "config" && parentDir == ".ssh"):
+		return FlavorSSHConfig
+	case baseFilename == "sshd_config" || parentDir == "sshd_config.d":
+		return FlavorSSHDConfig
+	case ext == ".desktop":
+		return FlavorDesktopEntry
+	case baseFilename == ".Xresources" || baseFilename == ".Xdefaults" || baseFilename == "Xresources":
+		return FlavorXresources
+	case baseFilename == ".tmux.conf" || baseFilename == "tmux.conf":
+		return FlavorTmux
+	case baseFilename == "config" && (parentDir == "i3" || parentDir == "sway"):
+		return FlavorI3
+	case baseFilename == "pacman.conf":
+		return FlavorPacman
+	case ext == ".rules" && parentDir == "rules.d" && hasS(dirs, "udev"):
+		return FlavorUdevRules
+	case baseFilename == ".htaccess" || baseFilename == "httpd.conf" || baseFilename == "apache2.conf" || hasS(dirs, "apache2") || hasS(dirs, "httpd"):
+		return FlavorApache
+	case baseFilename == "nginx.conf" || hasS(dirs, "nginx"):
+		return FlavorNginx
+	}
+	if len(data) > 64*1024 {
+		data = data[:64*1024]
+	}
+	var (
+		nginxMarkers, tmuxMarkers, cronLines, envLines, contentLines int
+		firstContent                                                 = true
+	)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' || trimmed[0] == ';' {
+			continue
+		}
+		contentLines++
+		if firstContent {
+			firstContent = false
+			switch {
+			case bytes.Equal(trimmed, []byte("[Desktop Entry]")):
+				return FlavorDesktopEntry
+			case bytes.Equal(trimmed, []byte("[Unit]")) || bytes.Equal(trimmed, []byte("[Service]")) || bytes.Equal(trimmed, []byte("[Install]")):
+				return FlavorSystemd
+			case bytes.Equal(trimmed, []byte("[options]")) && bytes.Contains(data, []byte("\n[core]")):
+				return FlavorPacman
+			}
+		}
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("<VirtualHost")) || bytes.HasPrefix(trimmed, []byte("<Directory")) || bytes.HasPrefix(trimmed, []byte("<IfModule")):
+			return FlavorApache
+		case bytes.Contains(trimmed, []byte("KERNEL==")) || bytes.Contains(trimmed, []byte("SUBSYSTEM==")) || bytes.HasPrefix(trimmed, []byte("ACTION==")):
+			return FlavorUdevRules
+		case bytes.HasPrefix(trimmed, []byte("bindsym ")) || bytes.HasPrefix(trimmed, []byte("set $mod ")):
+			return FlavorI3
+		case bytes.HasPrefix(trimmed, []byte("Host ")) && bytes.Contains(data, []byte("HostName ")):
+			return FlavorSSHConfig
+		case bytes.HasPrefix(trimmed, []byte("Defaults")) && bytes.Contains(data, []byte("ALL=(")):
+			return FlavorSudoers
+		case bytes.HasPrefix(trimmed, []byte("set -g ")) || bytes.HasPrefix(trimmed, []byte("set-option ")) || bytes.HasPrefix(trimmed, []byte("bind-key ")) || bytes.HasPrefix(trimmed, []byte("unbind ")):
+			tmuxMarkers++
+		case bytes.HasSuffix(trimmed, []byte("{")) && (bytes.HasPrefix(trimmed, []byte("server")) || bytes.HasPrefix(trimmed, []byte("http")) || bytes.HasPrefix(trimmed, []byte("events")) || bytes.HasPrefix(trimmed, []byte("location ")) || bytes.HasPrefix(trimmed, []byte("upstream "))):
+			nginxMarkers++
+		case isCronLine(trimmed):
+			cronLines++
+		case isEnvLine(trimmed):
+			envLines++
+		}
+	}
+	switch {
+	case nginxMarkers > 0:
+		return FlavorNginx
+	case tmuxMarkers > 1:
+		return FlavorTmux
+	case cronLines > 0 && cronLines*2 > contentLines-envLines:
+		// Most of the lines, apart from variables like MAILTO=root, must be crontab entries
+		return FlavorCrontab
+	}
+	return FlavorNone
+}
+
+// isEnvLine checks if the given line is a variable assignment, like MAILTO=root
+func isEnvLine(line []byte) bool {
+	name, _, found := bytes.Cut(line, []byte("="))
+	name = bytes.TrimSpace(name)
+	if !found || len(name) == 0 {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// isCronLine checks if the given line looks like a crontab entry, with five time fields and a
+// command, or a special time like @daily. Variable assignments, like MAILTO=root, are not counted.
+func isCronLine(line []byte) bool {
+	fields := bytes.Fields(line)
+	if len(fields) > 1 && bytes.HasPrefix(fields[0], []byte("@")) {
+		return hasS([]string{"@reboot", "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}, string(fields[0]))
+	}
+	if len(fields) < 6 {
+		return false
+	}
+	for _, field := range fields[:5] {
+		for _, c := range field {
+			if !(c >= '0' && c <= '9' || c == '*' || c == '/' || c == '-' || c == ',') {
+				return false
+			}
+		}
+	}
+	return true
+}
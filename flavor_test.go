@@ -0,0 +1,178 @@
+package mode
+
+import "testing"
+
+func TestDetectFlavor(t *testing.T) {
+	flavors := []struct {
+		name   string
+		data   string
+		flavor Flavor
+	}{
+		{".github/workflows/ci.yml", "", FlavorGitHubWorkflow},
+		{"ci.yml", "name: CI\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n", FlavorGitHubWorkflow},
+		{".gitlab-ci.yml", "", FlavorGitLabCI},
+		{"docker-compose.yml", "", FlavorCompose},
+		{"deploy/compose.override.yaml", "", FlavorCompose},
+		{"stack.yml", "services:\n  web:\n    image: nginx\nvolumes:\n  data:\n", FlavorCompose},
+		{"deployment.yaml", "# A deployment\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n", FlavorKubernetes},
+		{"charts/web/Chart.yaml", "", FlavorHelmChart},
+		{"charts/web/templates/service.yaml", "apiVersion: v1\nkind: Service\nmetadata:\n  name: {{ .Release.Name }}\n", FlavorHelmTemplate},
+		{"site.yml", "---\n- name: Web servers\n  hosts: web\n  tasks:\n    - name: Install nginx\n", FlavorAnsible},
+		{"roles/web/tasks/main.yml", "- name: Install nginx\n", FlavorAnsibleTasks},
+		{"api.yaml", "openapi: 3.0.3\ninfo:\n  title: API\n", FlavorOpenAPI30},
+		{"api.yaml", "openapi: \"3.1.0\"\ninfo:\n  title: API\n", FlavorOpenAPI31},
+		{"api.yml", "swagger: '2.0'\ninfo:\n  title: API\n", FlavorSwagger},
+		{"config.yml", "name: test\nitems:\n  - hosts: 3\n", FlavorNone},
+		{"main.go", "package main\n", FlavorNone},
+	}
+	for _, f := range flavors {
+		if flavor := DetectFlavor(f.name, []byte(f.data)); flavor != f.flavor {
+			t.Errorf("Expected %s for %s, got %s", f.flavor, f.name, flavor)
+		}
+	}
+}
+
+func TestFlavorSchema(t *testing.T) {
+	if FlavorGitHubWorkflow.Schema() != "https://json.schemastore.org/github-workflow.json" {
+		t.Fail()
+	}
+	if FlavorKubernetes.Schema() != "kubernetes" || FlavorNone.Schema() != "" {
+		t.Fail()
+	}
+	if FlavorCompose.Mode() != YAML || FlavorNone.Mode() != Blank {
+		t.Fail()
+	}
+	for f := FlavorNone; f < lastFlavor; f++ {
+		if f.String() == "?" {
+			t.Errorf("Flavor %d has no string", f)
+		}
+	}
+}
+
+func TestDetectJSONFlavor(t *testing.T) {
+	flavors := []struct {
+		name   string
+		data   string
+		flavor Flavor
+	}{
+		{"tsconfig.json", "", FlavorJSONC},
+		{"tsconfig.build.json", "", FlavorJSONC},
+		{".vscode/settings.json", "", FlavorJSONC},
+		{"settings.jsonc", "", FlavorJSONC},
+		{"config.json5", "", FlavorJSON5},
+		{"events.jsonl", "", FlavorJSONLines},
+		{"events.ndjson", "", FlavorJSONLines},
+		{"analysis.ipynb", "", FlavorNotebook},
+		{"data.json", "{\n  // a comment\n  \"a\": 1\n}\n", FlavorJSONC},
+		{"data.json", "{\n  \"a\": [1, 2,],\n}\n", FlavorJSONC},
+		{"data.json", "{\n  a: 'b',\n  n: 0x10,\n}\n", FlavorJSON5},
+		{"data.json", "{\"a\": 1}\n{\"a\": 2}\n", FlavorJSONLines},
+		{"data.json", "{\n  \"url\": \"http://example.com/*\",\n  \"n\": -1.5e10,\n  \"ok\": true\n}\n", FlavorNone},
+		{"", "{\"a\": 1}\n{\"a\": 2}\n", FlavorJSONLines},
+	}
+	for _, f := range flavors {
+		if flavor := DetectFlavor(f.name, []byte(f.data)); flavor != f.flavor {
+			t.Errorf("Expected %s for %s with %q, got %s", f.flavor, f.name, f.data, flavor)
+		}
+	}
+	if FlavorJSON5.Mode() != JSON {
+		t.Fail()
+	}
+}
+
+func TestDetectXMLFlavor(t *testing.T) {
+	flavors := []struct {
+		name   string
+		data   string
+		flavor Flavor
+	}{
+		{"logo.svg", "", FlavorSVG},
+		{"pom.xml", "", FlavorMavenPOM},
+		{"App.csproj", "", FlavorMSBuild},
+		{"app/src/main/AndroidManifest.xml", "", FlavorAndroidManifest},
+		{"Info.plist", "", FlavorPlist},
+		{"transform.xsl", "", FlavorXSLT},
+		{"types.xsd", "", FlavorXSD},
+		{"MainWindow.xaml", "", FlavorXAML},
+		{"icon.xml", "<?xml version=\"1.0\"?>\n<!-- An icon -->\n<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"10\"/>\n", FlavorSVG},
+		{"page.xml", "<html xmlns=\"http://www.w3.org/1999/xhtml\"><body/></html>", FlavorXHTML},
+		{"project.xml", "<project xmlns=\"http://maven.apache.org/POM/4.0.0\"></project>", FlavorMavenPOM},
+		{"build.xml", "<Project Sdk=\"Microsoft.NET.Sdk\"></Project>", FlavorMSBuild},
+		{"manifest.xml", "<manifest xmlns:android=\"http://schemas.android.com/apk/res/android\" package=\"a.b\"/>", FlavorAndroidManifest},
+		{"settings.xml", "<?xml version=\"1.0\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict/></plist>", FlavorPlist},
+		{"data.plist", "bplist00\x00\x01", FlavorPlist},
+		{"style.xml", "<xsl:stylesheet version=\"1.0\" xmlns:xsl=\"http://www.w3.org/1999/XSL/Transform\"/>", FlavorXSLT},
+		{"schema.xml", "<xs:schema xmlns:xs=\"http://www.w3.org/2001/XMLSchema\"/>", FlavorXSD},
+		{"feed.xml", "<rss version=\"2.0\"><channel/></rss>", FlavorRSS},
+		{"feed.xml", "<feed xmlns=\"http://www.w3.org/2005/Atom\"></feed>", FlavorAtom},
+		{"window.xml", "<Window xmlns=\"http://schemas.microsoft.com/winfx/2006/xaml/presentation\"/>", FlavorXAML},
+		{"main.ui.xml", "<?xml version=\"1.0\"?>\n<interface>\n  <object class=\"GtkWindow\"/>\n</interface>", FlavorGtkBuilder},
+		{"dialog.xml", "<interface>\n  <requires lib=\"gtk\" version=\"4.0\"/>\n</interface>", FlavorGtkBuilder},
+		{"org.example.Foo.xml", "<node>\n  <interface name=\"org.example.Foo\">\n    <method name=\"Bar\"/>\n  </interface>\n</node>", FlavorNone},
+		{"org.example.Foo.xml", "<interface name=\"org.example.Foo\">\n  <method name=\"Bar\"/>\n</interface>", FlavorNone},
+		{"data.xml", "<data><item/></data>", FlavorNone},
+		{"", "<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>", FlavorSVG},
+	}
+	for _, f := range flavors {
+		if flavor := DetectFlavor(f.name, []byte(f.data)); flavor != f.flavor {
+			t.Errorf("Expected %s for %s, got %s", f.flavor, f.name, flavor)
+		}
+	}
+	if FlavorSVG.Mode() != XML {
+		t.Fail()
+	}
+}
+
+func TestDetectConfigFlavor(t *testing.T) {
+	flavors := []struct {
+		name   string
+		data   string
+		flavor Flavor
+	}{
+		{"/etc/systemd/system/web.service", "", FlavorSystemd},
+		{"backup.timer", "", FlavorSystemd},
+		{"/etc/systemd/system/web.service.d/override.conf", "", FlavorSystemd},
+		{"/etc/nginx/sites-enabled/default", "", FlavorNginx},
+		{"/etc/nginx/nginx.conf", "", FlavorNginx},
+		{"site/.htaccess", "", FlavorApache},
+		{"/etc/apache2/sites-available/000-default.conf", "", FlavorApache},
+		{"/etc/ssh/ssh_config", "", FlavorSSHConfig},
+		{"/home/user/.ssh/config", "", FlavorSSHConfig},
+		{"/etc/ssh/sshd_config", "", FlavorSSHDConfig},
+		{"/etc/sudoers", "", FlavorSudoers},
+		{"/etc/sudoers.d/users", "", FlavorSudoers},
+		{"/etc/cron.d/backup", "", FlavorCrontab},
+		{"crontab", "", FlavorCrontab},
+		{"/etc/udev/rules.d/99-usb.rules", "", FlavorUdevRules},
+		{"firefox.desktop", "", FlavorDesktopEntry},
+		{".Xresources", "", FlavorXresources},
+		{".tmux.conf", "", FlavorTmux},
+		{"/home/user/.config/sway/config", "", FlavorI3},
+		{"/etc/pacman.conf", "", FlavorPacman},
+		{"Android.bp", "", FlavorAndroidBlueprint},
+		{"app.conf", "# Web server\nserver {\n    listen 80;\n    location / {\n    }\n}\n", FlavorNginx},
+		{"site.conf", "<VirtualHost *:80>\n    ServerName example.com\n</VirtualHost>\n", FlavorApache},
+		{"app.conf", "[Unit]\nDescription=App\n\n[Service]\nExecStart=/usr/bin/app\n", FlavorSystemd},
+		{"jobs.conf", "MAILTO=root\n# m h dom mon dow command\n*/5 * * * * /usr/bin/backup\n", FlavorCrontab},
+		{"keys.conf", "set $mod Mod4\nbindsym $mod+Return exec foot\n", FlavorI3},
+		{"term.conf", "set -g mouse on\nbind-key r source-file ~/.tmux.conf\n", FlavorTmux},
+		{"usb.conf", "SUBSYSTEM==\"usb\", ATTR{idVendor}==\"1234\", MODE=\"0666\"\n", FlavorUdevRules},
+		{"app.conf", "name = app\nport = 8080\n", FlavorNone},
+		{"app.conf", "# Runs every five minutes\n0 5 * * * backup\nname = app\nport = 8080\nworkers 4\nlisten localhost\n", FlavorNone},
+		{"jobs.conf", "SHELL=/bin/sh\nPATH=/usr/bin:/bin\n0 5 * * * backup\n", FlavorCrontab},
+	}
+	for _, f := range flavors {
+		if flavor := DetectFlavor(f.name, []byte(f.data)); flavor != f.flavor {
+			t.Errorf("Expected %s for %s, got %s", f.flavor, f.name, flavor)
+		}
+	}
+	if FlavorSudoers.Mode() != Config {
+		t.Fail()
+	}
+	if m := lastFlavor.Mode(); m != Blank {
+		t.Errorf("Expected Blank for an unknown flavor, got %s", m)
+	}
+	if m := Flavor(-1).Mode(); m != Blank {
+		t.Errorf("Expected Blank for an out-of-range flavor, got %s", m)
+	}
+}
@@ -0,0 +1,84 @@
+package mode
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// vendorDirectories are directory names that contain code that is not part of the project itself
+	vendorDirectories = []string{".git", ".yarn", "Godeps", "bower_components", "node_modules", "third_party", "vendor"}
+
+	// lockFilenames are files that are generated by package managers
+	lockFilenames = []string{"Cargo.lock", "Gemfile.lock", "Package.resolved", "Pipfile.lock", "Podfile.lock", "composer.lock", "flake.lock", "go.sum", "go.work.sum", "mix.lock", "npm-shrinkwrap.json", "package-lock.json", "packages.lock.json", "pnpm-lock.yaml", "poetry.lock", "pubspec.lock", "uv.lock", "yarn.lock"}
+
+	// generatedSuffixes are filename suffixes used by code generators, like protoc and tsc
+	generatedSuffixes = []string{".d.cts", ".d.mts", ".d.ts", ".gen.go", ".min.css", ".min.js", ".pb.cc", ".pb.go", ".pb.gw.go", ".pb.h", "_pb2.py", "_pb2_grpc.py", "_pb.js", "_pb.ts", "-min.js"}
+
+	// generatedMarkers are strings that code generators place near the top of the files they write
+	generatedMarkers = [][]byte{
+		[]byte("Generated by the protocol buffer compiler.  DO NOT EDIT!"),
+		[]byte("Generated by the gRPC"),
+		[]byte("Autogenerated by Thrift"),
+		[]byte("@generated"),
+		[]byte("This file is automatically generated"),
+		[]byte("This file was automatically generated"),
+	}
+)
+
+// IsVendored checks if the given path is in, or is, a vendor directory,
+// like "vendor/" or "node_modules/", containing code that is not part of the project itself
+func IsVendored(name string) bool {
+	for _, segment := range strings.Split(filepath.ToSlash(name), "/") {
+		if hasS(vendorDirectories, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGenerated checks if the given file looks like it was generated by a tool instead of
+// written by hand. Lockfiles, protoc and gRPC output, TypeScript declarations, minified JavaScript and CSS and files with a
+// "Code generated ... DO NOT EDIT." line are recognized. data may be nil, if only the name should be considered.
+func IsGenerated(name string, data []byte) bool {
+	baseFilename := path.Base(filepath.ToSlash(name))
+	if hasS(lockFilenames, baseFilename) {
+		return true
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(baseFilename, suffix) {
+			return true
+		}
+	}
+	if len(data) == 0 {
+		return false
+	}
+	if len(data) > 4096 {
+		data = data[:4096]
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		// See https://go.dev/s/generatedcode
+		if i := bytes.Index(line, []byte("Code generated ")); i >= 0 && bytes.HasSuffix(line[i:], []byte("DO NOT EDIT.")) {
+			return true
+		}
+		for _, marker := range generatedMarkers {
+			if bytes.Contains(line, marker) {
+				return true
+			}
+		}
+	}
+	switch filepath.Ext(baseFilename) {
+	case ".css", ".js", ".mjs":
+		return minified(data)
+	}
+	return false
+}
+
+// minified checks if the given data has very long lines on average, like minified JavaScript or CSS
+func minified(data []byte) bool {
+	lineCount := bytes.Count(data, []byte("\n")) + 1
+	return len(data)/lineCount > 110
+}
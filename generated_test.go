@@ -0,0 +1,60 @@
+package mode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsGenerated(t *testing.T) {
+	generated := []struct {
+		name string
+		data string
+	}{
+		{"stringer.go", "// Code generated by \"stringer -type=Mode\"; DO NOT EDIT.\n\npackage mode\n"},
+		{"api.pb.go", ""},
+		{"api.pb.cc", "// Generated by the protocol buffer compiler.  DO NOT EDIT!\n"},
+		{"api_pb2.py", ""},
+		{"types/index.d.ts", ""},
+		{"mocks.gen.go", ""},
+		{"go.sum", ""},
+		{"web/package-lock.json", "{}"},
+		{"Cargo.lock", ""},
+		{"jquery.min.js", ""},
+		{"bundle.js", strings.Repeat("var a=1;", 100)},
+		{"style.css", strings.Repeat("a{color:red}", 100)},
+	}
+	for _, g := range generated {
+		if !IsGenerated(g.name, []byte(g.data)) {
+			t.Errorf("Expected %s to be generated", g.name)
+		}
+	}
+	handwritten := []struct {
+		name string
+		data string
+	}{
+		{"main.go", "package main\n\nfunc main() {}\n"},
+		{"app.js", "var a = 1;\nvar b = 2;\n"},
+		{"go.mod", "module example.com/x\n"},
+		{"notes.txt", strings.Repeat("a long line of text ", 100)},
+	}
+	for _, h := range handwritten {
+		if IsGenerated(h.name, []byte(h.data)) {
+			t.Errorf("Expected %s to not be generated", h.name)
+		}
+	}
+}
+
+func TestIsVendored(t *testing.T) {
+	if !IsVendored("vendor/github.com/xyproto/lookslikegoasm/goasm.go") {
+		t.Fail()
+	}
+	if !IsVendored("web/node_modules/left-pad/index.js") {
+		t.Fail()
+	}
+	if !IsVendored("vendor") {
+		t.Fail()
+	}
+	if IsVendored("filename.go") || IsVendored("vendors.go") || IsVendored("src/vendoring/x.go") {
+		t.Fail()
+	}
+}
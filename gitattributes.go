@@ -0,0 +1,172 @@
+package mode
+
+import (
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// AttributeState is the state of a boolean attribute in a .gitattributes file
+type AttributeState int
+
+const (
+	AttributeUnspecified AttributeState = iota // the attribute is not mentioned for the path
+	AttributeSet                               // ie. "linguist-vendored" or "linguist-vendored=true"
+	AttributeUnset                             // ie. "-linguist-vendored" or "linguist-vendored=false"
+)
+
+// LinguistAttributes are the GitHub Linguist overrides from .gitattributes files that apply to a path
+type LinguistAttributes struct {
+	Language      Mode   // the mode given by linguist-language, if HasLanguage is true
+	LanguageName  string // the value of linguist-language, as written
+	HasLanguage   bool   // linguist-language is given, and is the name of a known mode
+	Vendored      AttributeState
+	Generated     AttributeState
+	Documentation AttributeState
+	Binary        AttributeState // set by "binary" or "-text", unset by "text"
+}
+
+// attributeRule is a single pattern line from a .gitattributes file
+type attributeRule struct {
+	base       string   // the directory of the .gitattributes file, relative to the root, or ""
+	segments   []string // the pattern, split into path segments
+	attributes []string // the attributes, as written, ie. "-diff" or "linguist-language=PHP"
+}
+
+// GitAttributes is a collection of patterns and attributes from .gitattributes files
+type GitAttributes struct {
+	rules []attributeRule
+}
+
+// ParseGitAttributes parses the contents of a .gitattributes file in the root directory
+func ParseGitAttributes(data []byte) *GitAttributes {
+	var ga GitAttributes
+	ga.Add(data, "")
+	return &ga
+}
+
+// Add parses the contents of a .gitattributes file, found in the given slash-separated
+// directory relative to the root, and adds the patterns to the collection.
+// Patterns that are added later take precedence, so files in subdirectories should be added last.
+func (ga *GitAttributes) Add(data []byte, dir string) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	for _, byteLine := range bytes.Split(data, []byte("\n")) {
+		line := strings.TrimSpace(string(byteLine))
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[attr]") {
+			continue
+		}
+		var pattern string
+		if strings.HasPrefix(line, `"`) {
+			// A quoted pattern, which may contain spaces
+			unquoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				continue
+			}
+			line = line[len(unquoted):]
+			pattern, _ = strconv.Unquote(unquoted)
+		} else {
+			fields := strings.Fields(line)
+			pattern, line = fields[0], line[len(fields[0]):]
+		}
+		// Negative patterns are not allowed in .gitattributes files
+		if pattern == "" || strings.HasPrefix(pattern, "!") {
+			continue
+		}
+		// A pattern without a slash matches at any level below the .gitattributes file
+		if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+			pattern = "**/" + pattern
+		}
+		ga.rules = append(ga.rules, attributeRule{
+			base:       dir,
+			segments:   strings.Split(strings.TrimPrefix(pattern, "/"), "/"),
+			attributes: strings.Fields(line),
+		})
+	}
+}
+
+// mentions checks if any of the rules mentions the given attribute with the given state
+func (ga *GitAttributes) mentions(attribute string, state AttributeState) bool {
+	if ga == nil {
+		return false
+	}
+	for _, rule := range ga.rules {
+		for _, attr := range rule.attributes {
+			if name, _, s := parseAttribute(attr); name == attribute && s == state {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseAttribute parses a single attribute, like "-diff", "!text" or "linguist-language=PHP".
+// Returns the attribute name, the value (if any) and the state.
+func parseAttribute(attr string) (string, string, AttributeState) {
+	switch {
+	case strings.HasPrefix(attr, "-"):
+		return attr[1:], "", AttributeUnset
+	case strings.HasPrefix(attr, "!"):
+		return attr[1:], "", AttributeUnspecified
+	}
+	name, value, found := strings.Cut(attr, "=")
+	if !found {
+		return name, "", AttributeSet
+	}
+	switch strings.ToLower(value) {
+	case "false", "0":
+		return name, value, AttributeUnset
+	}
+	return name, value, AttributeSet
+}
+
+// Resolve returns the linguist attributes for the given slash-separated path, relative to the root
+func (ga *GitAttributes) Resolve(name string) LinguistAttributes {
+	var la LinguistAttributes
+	if ga == nil {
+		return la
+	}
+	name = strings.Trim(path.Clean("/"+name), "/")
+	for _, rule := range ga.rules {
+		relative := name
+		if rule.base != "" {
+			if !strings.HasPrefix(name, rule.base+"/") {
+				continue
+			}
+			relative = name[len(rule.base)+1:]
+		}
+		if !matchSegments(rule.segments, strings.Split(relative, "/")) {
+			continue
+		}
+		for _, attr := range rule.attributes {
+			attrName, value, state := parseAttribute(attr)
+			switch attrName {
+			case "linguist-language":
+				if state == AttributeSet && value != "" {
+					la.LanguageName = value
+					la.Language, la.HasLanguage = ParseMode(value)
+				} else {
+					la.Language, la.LanguageName, la.HasLanguage = Blank, "", false
+				}
+			case "linguist-vendored":
+				la.Vendored = state
+			case "linguist-generated":
+				la.Generated = state
+			case "linguist-documentation":
+				la.Documentation = state
+			case "binary":
+				if state == AttributeSet {
+					la.Binary = AttributeSet
+				}
+			case "text":
+				switch state {
+				case AttributeSet:
+					la.Binary = AttributeUnset
+				case AttributeUnset:
+					la.Binary = AttributeSet
+				}
+			}
+		}
+	}
+	return la
+}
@@ -0,0 +1,50 @@
+package mode
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGitAttributes(t *testing.T) {
+	ga := ParseGitAttributes([]byte("# comment\n*.inc linguist-language=PHP\ndocs/** linguist-documentation\n*.pb.go linguist-generated=true\nvendor/** -linguist-vendored\n*.png binary\n\"with space.txt\" linguist-language=Emacs-Lisp\n"))
+	ga.Add([]byte("*.inc -linguist-language\n"), "legacy")
+	if la := ga.Resolve("src/header.inc"); !la.HasLanguage || la.Language != PHP {
+		t.Errorf("Expected PHP, got %+v", la)
+	}
+	if la := ga.Resolve("legacy/header.inc"); la.HasLanguage {
+		t.Errorf("Expected no language override, got %+v", la)
+	}
+	if la := ga.Resolve("docs/guide/index.md"); la.Documentation != AttributeSet {
+		t.Errorf("Expected documentation, got %+v", la)
+	}
+	if la := ga.Resolve("api/api.pb.go"); la.Generated != AttributeSet || la.Documentation != AttributeUnspecified {
+		t.Errorf("Expected generated, got %+v", la)
+	}
+	if la := ga.Resolve("vendor/x/x.go"); la.Vendored != AttributeUnset {
+		t.Errorf("Expected not vendored, got %+v", la)
+	}
+	if la := ga.Resolve("img/logo.png"); la.Binary != AttributeSet {
+		t.Errorf("Expected binary, got %+v", la)
+	}
+	if la := ga.Resolve("with space.txt"); la.Language != Lisp {
+		t.Errorf("Expected Lisp, got %+v", la)
+	}
+}
+
+func TestAnalyzeGitAttributes(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitattributes":    {Data: []byte("*.inc linguist-language=PHP\nvendor/** -linguist-vendored\nmain.go linguist-generated\n")},
+		"main.go":           {Data: []byte(strings.Repeat("package main\n", 100))},
+		"header.inc":        {Data: []byte("<?php echo 1; ?>\n")},
+		"vendor/lib/x.sh":   {Data: []byte("#!/bin/sh\necho x\n")},
+		"node_modules/x.js": {Data: []byte("var x = 1;\n")},
+	}
+	b, err := Analyze(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Languages) != 2 || b.Languages[0].Mode != PHP || b.Languages[1].Mode != Shell {
+		t.Fatalf("Expected PHP and Shell, got %v", b.Languages)
+	}
+}
@@ -0,0 +1,129 @@
+package mode
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// ignoreRule is a single pattern from a .gitignore or .ignore file
+type ignoreRule struct {
+	base     string   // the directory of the ignore file, relative to the root, or ""
+	segments []string // the pattern, split into path segments
+	negate   bool     // the pattern started with "!"
+	dirOnly  bool     // the pattern ended with "/"
+}
+
+// IgnoreRules is a collection of patterns from .gitignore and .ignore files (the Ignore mode)
+type IgnoreRules struct {
+	rules []ignoreRule
+}
+
+// ParseIgnore parses the contents of a .gitignore or .ignore file in the root directory
+func ParseIgnore(data []byte) *IgnoreRules {
+	var r IgnoreRules
+	r.Add(data, "")
+	return &r
+}
+
+// Add parses the contents of a .gitignore or .ignore file, found in the given
+// slash-separated directory relative to the root, and adds the patterns to the collection.
+// Patterns that are added later take precedence, so files in subdirectories should be added last.
+func (r *IgnoreRules) Add(data []byte, dir string) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	for _, byteLine := range bytes.Split(data, []byte("\n")) {
+		line := strings.TrimRight(string(byteLine), "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		rule.base = dir
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// A pattern without a slash matches at any level below the ignore file
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		rule.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		r.rules = append(r.rules, rule)
+	}
+}
+
+// Ignored checks if the given slash-separated path, relative to the root, is ignored.
+// isDir should be true if the path is a directory.
+// A path is also ignored if one of its parent directories is ignored.
+func (r *IgnoreRules) Ignored(name string, isDir bool) bool {
+	if r == nil || len(r.rules) == 0 {
+		return false
+	}
+	name = strings.Trim(path.Clean("/"+name), "/")
+	segments := strings.Split(name, "/")
+	for i := 1; i < len(segments); i++ {
+		if r.match(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.match(name, isDir)
+}
+
+// match checks if the last matching rule for the given path ignores it
+func (r *IgnoreRules) match(name string, isDir bool) bool {
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		rule := r.rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		relative := name
+		if rule.base != "" {
+			if !strings.HasPrefix(name, rule.base+"/") {
+				continue
+			}
+			relative = name[len(rule.base)+1:]
+		}
+		if matchSegments(rule.segments, strings.Split(relative, "/")) {
+			return !rule.negate
+		}
+	}
+	return false
+}
+
+// matchSegments checks if the given path segments match the given pattern segments.
+// "**" matches zero or more path segments, other segments are matched with path.Match.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range segments {
+				if matchSegments(pattern, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
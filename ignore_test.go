@@ -0,0 +1,35 @@
+package mode
+
+import (
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	r := ParseIgnore([]byte("# comment\n*.o\n/build\ndocs/\n!keep.o\nsub/**/gen.go\n"))
+	r.Add([]byte("local.txt\n"), "sub")
+	ignored := map[string]bool{
+		"main.o":           true,
+		"a/b/c.o":          true,
+		"keep.o":           false,
+		"build":            true,
+		"build/main.go":    true,
+		"a/build":          false,
+		"docs/index.md":    true,
+		"main.go":          false,
+		"sub/x/y/gen.go":   true,
+		"sub/gen.go":       true,
+		"sub/local.txt":    true,
+		"sub/a/local.txt":  true,
+		"other/local.txt":  false,
+		"other/docs":       false,
+		"other/docs/x.txt": true,
+	}
+	for name, expected := range ignored {
+		if r.Ignored(name, false) != expected {
+			t.Errorf("Expected Ignored(%q) to be %v", name, expected)
+		}
+	}
+	if !r.Ignored("other/docs", true) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,404 @@
+package mode
+
+import (
+	"bytes"
+	"strings"
+)
+
+// LineKind is the kind of a single line of source code, as found by ClassifyLines
+type LineKind int
+
+const (
+	CodeLine      LineKind = iota // CodeLine is a line that contains code
+	CommentLine                   // CommentLine is a line that only contains comments
+	BlankLine                     // BlankLine is a line that only contains whitespace
+	StringLine                    // StringLine is a line inside of a multi-line string
+	DocstringLine                 // DocstringLine is a line that is part of a docstring
+)
+
+// String returns a short lowercase string representing the given line kind
+func (k LineKind) String() string {
+	switch k {
+	case CodeLine:
+		return "code"
+	case CommentLine:
+		return "comment"
+	case BlankLine:
+		return "blank"
+	case StringLine:
+		return "string"
+	case DocstringLine:
+		return "docstring"
+	default:
+		return "?"
+	}
+}
+
+// blockComment is a pair of start and end markers for a block comment
+type blockComment struct {
+	start, end string
+	nested     bool // can block comments of this kind be nested?
+}
+
+// syntax describes how comments and strings are written for a Mode
+type syntax struct {
+	lineComments   []string       // markers that start a comment that runs to the end of the line
+	lineStarts     []string       // comment markers that only count at the start of a line
+	firstColumn    bool           // lineStarts must be in the very first column, not after indentation
+	blockComments  []blockComment // block comments
+	lineBlocks     []blockComment // block comments where both markers must be at the very start of a line
+	quotes         []string       // delimiters for strings that can not span several lines
+	multiQuotes    []string       // delimiters for strings that can span several lines, the same at both ends
+	docstrings     bool           // is a multi-line string at the start of a line a docstring?
+	noQuoteEscapes bool           // backslash does not escape quotes
+	longBrackets   bool           // block comments are Lua long brackets, like --[==[ and ]==]
+}
+
+var (
+	cBlock      = blockComment{"/*", "*/", false}
+	nestedBlock = blockComment{"/*", "*/", true}
+	htmlBlock   = blockComment{"<!--", "-->", false}
+	mlBlock     = blockComment{"(*", "*)", true}
+	haskBlock   = blockComment{"{-", "-}", true}
+	luaBlock    = blockComment{"--[", "]", false}
+	dq          = []string{`"`}
+	dqsq        = []string{`"`, `'`}
+
+	cSyntax      = syntax{lineComments: []string{"//"}, blockComments: []blockComment{cBlock}, quotes: dqsq}
+	nestedSyntax = syntax{lineComments: []string{"//"}, blockComments: []blockComment{nestedBlock}, quotes: dq}
+	hashSyntax   = syntax{lineComments: []string{"#"}, quotes: dqsq}
+	dashSyntax   = syntax{lineComments: []string{"--"}, quotes: dqsq}
+	semiSyntax   = syntax{lineComments: []string{";"}, quotes: dq}
+	xmlSyntax    = syntax{blockComments: []blockComment{htmlBlock}}
+)
+
+// syntax returns the comment and string syntax for the given Mode.
+// The zero value is returned for modes without any comments, like Text.
+func (m Mode) syntax() syntax {
+	switch m {
+	case AIDL, Arduino, Beef, Blueprint, C, C3, Chuck, CS, Cpp, Dingo, Faust, Garnet, Gradle, Groovy, HIDL, Haxe, Jakt, Java, Koka, ObjC, Pkl, Protobuf, Shader, SuperCollider, Tim, V, WGSL:
+		return cSyntax
+	case Go, JavaScript, TypeScript:
+		s := cSyntax
+		s.multiQuotes = []string{"`"}
+		return s
+	case GoAssembly:
+		return syntax{lineComments: []string{"//"}, blockComments: []blockComment{cBlock}, quotes: dq}
+	case Rust, Odin:
+		return nestedSyntax
+	case Swift, Kotlin, Scala, Dart:
+		s := nestedSyntax
+		s.multiQuotes = []string{`"""`}
+		if m == Dart {
+			s.multiQuotes = []string{`"""`, `'''`}
+			s.quotes = dqsq
+		}
+		return s
+	case D:
+		return syntax{lineComments: []string{"//"}, blockComments: []blockComment{cBlock, {"/+", "+/", true}}, quotes: dq, multiQuotes: []string{"`"}}
+	case Gleam, Hare, Zig:
+		return syntax{lineComments: []string{"//"}, quotes: dq}
+	case CSS:
+		return syntax{blockComments: []blockComment{cBlock}, quotes: dqsq}
+	case PHP:
+		return syntax{lineComments: []string{"//", "#"}, blockComments: []blockComment{cBlock}, quotes: dqsq}
+	case HCL:
+		return syntax{lineComments: []string{"#", "//"}, blockComments: []blockComment{cBlock}, quotes: dq}
+	case Nix:
+		return syntax{lineComments: []string{"#"}, blockComments: []blockComment{cBlock}, quotes: dq, multiQuotes: []string{"''"}}
+	case POV:
+		return syntax{lineComments: []string{"//"}, blockComments: []blockComment{nestedBlock}, quotes: dq}
+	case Python, Mojo, Starlark, Bazel:
+		return syntax{lineComments: []string{"#"}, quotes: dqsq, multiQuotes: []string{`"""`, `'''`}, docstrings: true}
+	case Ruby:
+		return syntax{lineComments: []string{"#"}, lineBlocks: []blockComment{{"=begin", "=end", false}}, quotes: dqsq}
+	case Perl:
+		pod := make([]blockComment, 0, 7)
+		for _, start := range []string{"=pod", "=head", "=begin", "=over", "=item", "=for", "=encoding"} {
+			pod = append(pod, blockComment{start, "=cut", false})
+		}
+		return syntax{lineComments: []string{"#"}, lineBlocks: pod, quotes: dqsq}
+	case Elixir:
+		return syntax{lineComments: []string{"#"}, quotes: dqsq, multiQuotes: []string{`"""`, `'''`}}
+	case Julia:
+		return syntax{lineComments: []string{"#"}, blockComments: []blockComment{{"#=", "=#", true}}, quotes: dq, multiQuotes: []string{`"""`}, docstrings: true}
+	case Nim:
+		return syntax{lineComments: []string{"#"}, blockComments: []blockComment{{"#[", "]#", true}}, quotes: dq, multiQuotes: []string{`"""`}}
+	case CMake:
+		return syntax{lineComments: []string{"#"}, blockComments: []blockComment{{"#[[", "]]", false}}, quotes: dq}
+	case TOML:
+		return syntax{lineComments: []string{"#"}, quotes: dqsq, multiQuotes: []string{`"""`, `'''`}}
+	case Caddyfile, CodeOwners, Config, Crystal, Docker, Email, GDScript, Git, Ignore, Inko, Janet, Just, Make, Nushell, Ollama, PolicyLanguage, Procfile, R, Shell, Spec, Subversion, YAML:
+		return hashSyntax
+	case Ini:
+		return syntax{lineComments: []string{";", "#"}, quotes: dq}
+	case Lua, Nmap, Teal, Terra:
+		return syntax{lineComments: []string{"--"}, blockComments: []blockComment{luaBlock}, quotes: dqsq, longBrackets: true}
+	case Haskell, Agda, Elm, Dhall:
+		return syntax{lineComments: []string{"--"}, blockComments: []blockComment{haskBlock}, quotes: dq}
+	case SQL:
+		return syntax{lineComments: []string{"--"}, blockComments: []blockComment{cBlock}, quotes: dqsq, noQuoteEscapes: true}
+	case Ada:
+		return dashSyntax
+	case Lisp, Scheme:
+		return syntax{lineComments: []string{";"}, blockComments: []blockComment{{"#|", "|#", true}}, quotes: dq}
+	case Clojure, Assembly:
+		return semiSyntax
+	case CSound:
+		return syntax{lineComments: []string{";", "//"}, blockComments: []blockComment{cBlock}, quotes: dq}
+	case Erlang, ABC:
+		return syntax{lineComments: []string{"%"}, quotes: dq}
+	case Prolog:
+		return syntax{lineComments: []string{"%"}, blockComments: []blockComment{cBlock}, quotes: dqsq}
+	case Lilypond:
+		return syntax{lineComments: []string{"%"}, blockComments: []blockComment{{"%{", "%}", false}}, quotes: dq}
+	case M4:
+		return syntax{lineComments: []string{"dnl", "#"}}
+	case OCaml, StandardML:
+		return syntax{blockComments: []blockComment{mlBlock}, quotes: dq}
+	case FSharp:
+		return syntax{lineComments: []string{"//"}, blockComments: []blockComment{mlBlock}, quotes: dq, multiQuotes: []string{`"""`}}
+	case ObjectPascal:
+		return syntax{lineComments: []string{"//"}, blockComments: []blockComment{{"{", "}", false}, mlBlock}, quotes: []string{"'"}, noQuoteEscapes: true}
+	case Basic:
+		return syntax{lineComments: []string{"'"}, lineStarts: []string{"REM ", "rem ", "Rem "}, quotes: dq, noQuoteEscapes: true}
+	case Bat:
+		return syntax{lineStarts: []string{"REM", "rem", "@REM", "@rem", "::"}, quotes: dq, noQuoteEscapes: true}
+	case Vim:
+		return syntax{lineStarts: []string{`"`}, quotes: []string{"'"}}
+	case Fortran77:
+		return syntax{lineComments: []string{"!"}, lineStarts: []string{"C", "c", "*"}, firstColumn: true, quotes: dqsq}
+	case Fortran90:
+		return syntax{lineComments: []string{"!"}, quotes: dqsq}
+	case COBOL:
+		return syntax{lineComments: []string{"*>"}, quotes: dqsq}
+	case Algol68:
+		return syntax{blockComments: []blockComment{{"#", "#", false}}, quotes: dq}
+	case Nroff, ManPage:
+		return syntax{lineStarts: []string{`.\"`, `'\"`, `\"`}}
+	case ERB, HTML, XML, Markdown:
+		return xmlSyntax
+	}
+	return syntax{}
+}
+
+// LineComment returns the preferred marker for commenting out a single line
+// in the given Mode, or an empty string if the mode has no line comments.
+func (m Mode) LineComment() string {
+	s := m.syntax()
+	if len(s.lineComments) > 0 {
+		return s.lineComments[0]
+	}
+	if len(s.lineStarts) > 0 {
+		return s.lineStarts[0]
+	}
+	return ""
+}
+
+// ClassifyLines splits the given data into lines and returns the kind of each line,
+// using the comment and string syntax of the given Mode.
+// A trailing newline does not count as an extra line.
+func (m Mode) ClassifyLines(data []byte) []LineKind {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	var (
+		s     = m.syntax()
+		kinds = make([]LineKind, len(lines))
+		c     classifier
+	)
+	for i, line := range lines {
+		kinds[i] = c.classify(&s, bytes.TrimSuffix(line, []byte("\r")))
+	}
+	return kinds
+}
+
+// classifier keeps track of block comments and strings that span several lines
+type classifier struct {
+	block     *blockComment // the current block comment, or nil
+	end       string        // the end marker of the current block comment
+	lineBlock *blockComment // the current line-start block comment, or nil
+	depth     int           // nesting depth of the current block comment
+	quote     string        // the delimiter of the current multi-line string, if any
+	doc       bool          // is the current multi-line string a docstring?
+}
+
+// hasAt checks if the given line has the given marker at position i
+func hasAt(line []byte, i int, marker string) bool {
+	return marker != "" && len(line)-i >= len(marker) && string(line[i:i+len(marker)]) == marker
+}
+
+// classify returns the LineKind of the given line, and updates the state of the classifier
+func (c *classifier) classify(s *syntax, line []byte) LineKind {
+	if c.lineBlock != nil {
+		if bytes.HasPrefix(line, []byte(c.lineBlock.end)) {
+			c.lineBlock = nil
+		}
+		return CommentLine
+	}
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 {
+		if c.quote != "" {
+			if c.doc {
+				return DocstringLine
+			}
+			return StringLine
+		}
+		return BlankLine
+	}
+	if c.block == nil && c.quote == "" {
+		for i := range s.lineBlocks {
+			if bytes.HasPrefix(line, []byte(s.lineBlocks[i].start)) {
+				c.lineBlock = &s.lineBlocks[i]
+				return CommentLine
+			}
+		}
+		start := trimmed
+		if s.firstColumn {
+			start = line
+		}
+		for _, marker := range s.lineStarts {
+			if bytes.HasPrefix(start, []byte(marker)) {
+				return CommentLine
+			}
+		}
+	}
+	var code, comment, str, doc bool
+	if c.block != nil {
+		comment = true
+	}
+	if c.quote != "" {
+		if c.doc {
+			doc = true
+		} else {
+			str = true
+		}
+	}
+	for i := 0; i < len(line); {
+		switch {
+		case c.block != nil:
+			if c.block.nested && hasAt(line, i, c.block.start) {
+				c.depth++
+				i += len(c.block.start)
+			} else if hasAt(line, i, c.end) {
+				c.depth--
+				i += len(c.end)
+				if c.depth <= 0 {
+					c.block = nil
+				}
+			} else {
+				i++
+			}
+			continue
+		case c.quote != "":
+			end := bytes.Index(line[i:], []byte(c.quote))
+			if end < 0 {
+				i = len(line)
+			} else {
+				i += end + len(c.quote)
+				c.quote = ""
+				c.doc = false
+			}
+			continue
+		case line[i] == ' ' || line[i] == '\t':
+			i++
+			continue
+		}
+		if n, ok := c.startBlock(s, line, i); ok {
+			comment = true
+			i += n
+			continue
+		}
+		if hasAnyAt(line, i, s.lineComments) != "" {
+			comment = true
+			i = len(line)
+			continue
+		}
+		if q := hasAnyAt(line, i, s.multiQuotes); q != "" {
+			if s.docstrings && !code {
+				doc = true
+				c.doc = true
+			} else {
+				code = true
+			}
+			c.quote = q
+			i += len(q)
+			continue
+		}
+		code = true
+		if q := hasAnyAt(line, i, s.quotes); q != "" {
+			i = skipString(line, i+len(q), q, !s.noQuoteEscapes)
+			continue
+		}
+		i++
+	}
+	switch {
+	case code:
+		return CodeLine
+	case doc:
+		return DocstringLine
+	case str:
+		return StringLine
+	case comment:
+		return CommentLine
+	}
+	return BlankLine
+}
+
+// startBlock checks if a block comment starts at position i in the given line.
+// Returns the length of the start marker and true if it does.
+func (c *classifier) startBlock(s *syntax, line []byte, i int) (int, bool) {
+	for j := range s.blockComments {
+		b := &s.blockComments[j]
+		if !hasAt(line, i, b.start) {
+			continue
+		}
+		n, end := len(b.start), b.end
+		if s.longBrackets {
+			// A long bracket, like --[==[, which ends with ]==]
+			level := 0
+			for i+n+level < len(line) && line[i+n+level] == '=' {
+				level++
+			}
+			if !hasAt(line, i+n+level, "[") {
+				continue
+			}
+			n += level + 1
+			end = b.end + strings.Repeat("=", level) + b.end
+		}
+		c.block, c.end, c.depth = b, end, 1
+		return n, true
+	}
+	return 0, false
+}
+
+// hasAnyAt returns the first of the given markers found at position i in the given line,
+// or an empty string if none are found. Longer markers should come first.
+func hasAnyAt(line []byte, i int, markers []string) string {
+	for _, marker := range markers {
+		if hasAt(line, i, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// skipString returns the position right after the end of the string that starts at
+// position i, or the length of the line if the string does not end on this line.
+func skipString(line []byte, i int, quote string, escapes bool) int {
+	for i < len(line) {
+		if escapes && line[i] == '\\' {
+			i += 2
+			continue
+		}
+		if hasAt(line, i, quote) {
+			return i + len(quote)
+		}
+		i++
+	}
+	return len(line)
+}
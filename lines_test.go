@@ -0,0 +1,50 @@
+package mode
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClassifyLines(t *testing.T) {
+	tests := []struct {
+		m     Mode
+		data  string
+		kinds []LineKind
+	}{
+		{Go, "// Package x\npackage x\n\n/* a\n   b */\nvar s = `\nraw // not a comment\n`\n", []LineKind{CommentLine, CodeLine, BlankLine, CommentLine, CommentLine, CodeLine, StringLine, StringLine}},
+		{Go, "x := \"// not a comment\" // but this is\n", []LineKind{CodeLine}},
+		{C, "/* one */ int x;\n/* two */\n", []LineKind{CodeLine, CommentLine}},
+		{Python, "\"\"\"Docstring\n\nmore\n\"\"\"\nx = '#'  # comment\n# only a comment\n", []LineKind{DocstringLine, DocstringLine, DocstringLine, DocstringLine, CodeLine, CommentLine}},
+		{Python, "s = \"\"\"\ntext\n\"\"\"\n", []LineKind{CodeLine, StringLine, StringLine}},
+		{Lua, "-- comment\n--[[ block\nstill ]]\nprint(1)\n", []LineKind{CommentLine, CommentLine, CommentLine, CodeLine}},
+		{Lua, "--[==[ long\ncomment ]] still\n]==]\nx=1\n--[x\n", []LineKind{CommentLine, CommentLine, CommentLine, CodeLine, CommentLine}},
+		{Julia, "#= block\n#= nested =#\nstill =#\n\"\"\"\n    f(x)\n\"\"\"\nf(x) = x # comment\n", []LineKind{CommentLine, CommentLine, CommentLine, DocstringLine, DocstringLine, DocstringLine, CodeLine}},
+		{Lisp, "; comment\n(defun f () 1)\n#| a\n#| nested |#\n|#\n", []LineKind{CommentLine, CodeLine, CommentLine, CommentLine, CommentLine}},
+		{Haskell, "{- a {- b -} c -}\nmain = pure ()\n", []LineKind{CommentLine, CodeLine}},
+		{Ruby, "=begin\ndocs\n=end\nputs 1\n", []LineKind{CommentLine, CommentLine, CommentLine, CodeLine}},
+		{Text, "hello\r\n\r\n", []LineKind{CodeLine, BlankLine}},
+	}
+	for _, test := range tests {
+		if kinds := test.m.ClassifyLines([]byte(test.data)); !slices.Equal(kinds, test.kinds) {
+			t.Errorf("%s: expected %v, got %v for %q", test.m, test.kinds, kinds, test.data)
+		}
+	}
+	if kinds := Mode(Go).ClassifyLines(nil); len(kinds) != 0 {
+		t.Fail()
+	}
+}
+
+func TestLineComment(t *testing.T) {
+	if Mode(Go).LineComment() != "//" {
+		t.Fail()
+	}
+	if Mode(Shell).LineComment() != "#" {
+		t.Fail()
+	}
+	if Mode(Lua).LineComment() != "--" {
+		t.Fail()
+	}
+	if Mode(Text).LineComment() != "" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,369 @@
+package mode
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LogFormat is the format of the lines in a log file
+type LogFormat int
+
+const (
+	LogFormatNone       LogFormat = iota // not a known log format
+	LogFormatSyslog                      // syslog, RFC 3164, like "Jan  2 15:04:05 host sshd[42]: message"
+	LogFormatSyslog5424                  // syslog, RFC 5424, like "<34>1 2003-10-11T22:14:15.003Z host app - ID47 - message"
+	LogFormatJournald                    // the journald export format, from journalctl -o export
+	LogFormatCombined                    // Apache and nginx access logs, in the common or combined format
+	LogFormatKlog                        // klog, as used by Kubernetes, like "I0102 15:04:05.123456 1 main.go:12] message"
+	LogFormatPython                      // the Python logging module, like "INFO:root:message"
+	LogFormatGo                          // the default format of the Go log package, like "2009/11/10 23:00:00 message"
+	LogFormatJSON                        // JSON lines, with a level or a timestamp in each object
+	LogFormatLogfmt                      // logfmt, like "ts=2024-01-02T15:04:05Z level=info msg=started"
+	lastLogFormat                        // lastLogFormat is not a log format, it is used for iterating over all log formats
+)
+
+// LogLevel is the severity of a line in a log file
+type LogLevel int
+
+const (
+	LogLevelNone     LogLevel = iota // no level was found
+	LogLevelTrace                    // trace
+	LogLevelDebug                    // debug
+	LogLevelInfo                     // info
+	LogLevelNotice                   // notice
+	LogLevelWarning                  // warning
+	LogLevelError                    // error
+	LogLevelCritical                 // critical, fatal, alert, emergency and panic
+)
+
+// LogLine is the result of parsing a line in a log file. The start and end fields are byte offsets
+// into the line, for coloring the timestamp and the level. They are -1 if there is no timestamp or level.
+type LogLine struct {
+	Level          LogLevel
+	TimestampStart int
+	TimestampEnd   int
+	LevelStart     int
+	LevelEnd       int
+}
+
+// klogLevels maps the first letter of klog lines to levels
+var klogLevels = map[byte]LogLevel{'I': LogLevelInfo, 'W': LogLevelWarning, 'E': LogLevelError, 'F': LogLevelCritical}
+
+// logSampleLines is the number of non-empty lines that DetectLogFormat looks at
+const logSampleLines = 50
+
+// LogSniffLimit is the number of bytes at the start of the data that DetectLogFormat looks at
+var LogSniffLimit = 64 * 1024
+
+var (
+	syslogRegexp      = regexp.MustCompile(`^(?:<(\d{1,3})>)?((?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) [ \d]\d \d\d:\d\d:\d\d) \S+ [^\s:\[]+(?:\[\d+\])?: `)
+	syslog5424Regexp  = regexp.MustCompile(`^<(\d{1,3})>1 (\S+) \S+ \S+ \S+ \S+ `)
+	journaldRegexp    = regexp.MustCompile(`^(_{0,2}[A-Z][A-Z0-9_]*)=(.*)$`)
+	combinedRegexp    = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "[^"]*" (\d{3}) (?:\d+|-)`)
+	klogRegexp        = regexp.MustCompile(`^([IWEF])(\d{4} \d\d:\d\d:\d\d\.\d{6}) +\d+ [^ \]]+:\d+\] `)
+	pythonRegexp      = regexp.MustCompile(`^(\d{4}-\d\d-\d\d \d\d:\d\d:\d\d,\d{3})\b.*?\b(DEBUG|INFO|WARNING|ERROR|CRITICAL)\b`)
+	pythonBasicRegexp = regexp.MustCompile(`^(DEBUG|INFO|WARNING|ERROR|CRITICAL):[^:\s]*:`)
+	goLogRegexp       = regexp.MustCompile(`^(\d{4}/\d\d/\d\d \d\d:\d\d:\d\d(?:\.\d+)?) `)
+	logfmtRegexp      = regexp.MustCompile(`([A-Za-z_][\w.\-]*)=("(?:[^"\\]|\\.)*"|[^\s"]*)`)
+	jsonLevelRegexp   = regexp.MustCompile(`"(?:level|lvl|severity|levelname|log\.level)"\s*:\s*"([^"]*)"`)
+	jsonTimeRegexp    = regexp.MustCompile(`"(?:time|ts|timestamp|@timestamp|t)"\s*:\s*(?:"([^"]*)"|([\d.eE+\-]+))`)
+	levelWordRegexp   = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|ERR|CRIT|CRITICAL|FATAL|PANIC|ALERT|EMERG)\b|\[(trace|debug|info|notice|warn|warning|error|crit|alert|emerg)\]`)
+)
+
+// String returns a short string representing the given log format
+func (f LogFormat) String() string {
+	switch f {
+	case LogFormatNone:
+		return "-"
+	case LogFormatSyslog:
+		return "syslog"
+	case LogFormatSyslog5424:
+		return "syslog (RFC 5424)"
+	case LogFormatJournald:
+		return "journald export"
+	case LogFormatCombined:
+		return "access log"
+	case LogFormatKlog:
+		return "klog"
+	case LogFormatPython:
+		return "Python logging"
+	case LogFormatGo:
+		return "Go log"
+	case LogFormatJSON:
+		return "JSON lines"
+	case LogFormatLogfmt:
+		return "logfmt"
+	default:
+		return "?"
+	}
+}
+
+// String returns a short string representing the given log level
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelNone:
+		return "-"
+	case LogLevelTrace:
+		return "trace"
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelNotice:
+		return "notice"
+	case LogLevelWarning:
+		return "warning"
+	case LogLevelError:
+		return "error"
+	case LogLevelCritical:
+		return "critical"
+	default:
+		return "?"
+	}
+}
+
+// DetectLogFormat looks at the first lines of the given data, up to LogSniffLimit bytes, and returns
+// the log format that most of them are in, or LogFormatNone. The first non-empty line must be in
+// that format, while later lines may be continuations, like stack traces.
+func DetectLogFormat(data []byte) LogFormat {
+	data = sniffPrefix(data, LogSniffLimit)
+	var (
+		counts     [lastLogFormat]int
+		firstLine  [lastLogFormat]bool
+		considered int
+		hasCursor  bool
+	)
+	for len(data) > 0 && considered < logSampleLines {
+		var line []byte
+		line, data, _ = bytes.Cut(data, []byte("\n"))
+		s := strings.TrimSuffix(string(line), "\r")
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if strings.HasPrefix(s, "__CURSOR=") || strings.HasPrefix(s, "__REALTIME_TIMESTAMP=") {
+			hasCursor = true
+		}
+		for f := LogFormatSyslog; f < lastLogFormat; f++ {
+			if _, ok := f.parse(s); ok {
+				counts[f]++
+				firstLine[f] = firstLine[f] || considered == 0
+			}
+		}
+		considered++
+	}
+	if !hasCursor {
+		// Lines like KEY=value are only journald exports if there are journald fields
+		counts[LogFormatJournald] = 0
+	}
+	best := LogFormatNone
+	for f := LogFormatSyslog; f < lastLogFormat; f++ {
+		if counts[f] > counts[best] && firstLine[f] {
+			best = f
+		}
+	}
+	if best == LogFormatNone || counts[best]*3 < considered {
+		return LogFormatNone
+	}
+	return best
+}
+
+// ParseLine finds the timestamp and the level in the given line, which is in this log format.
+// Lines that are not in the format, like stack traces, only get a level if a level word is found.
+func (f LogFormat) ParseLine(line string) LogLine {
+	if l, ok := f.parse(line); ok {
+		return l
+	}
+	l := LogLine{TimestampStart: -1, TimestampEnd: -1}
+	l.findLevelWord(line, 0)
+	return l
+}
+
+// parse finds the timestamp and the level in the given line, and returns true if the line is in this log format
+func (f LogFormat) parse(line string) (LogLine, bool) {
+	l := LogLine{TimestampStart: -1, TimestampEnd: -1, LevelStart: -1, LevelEnd: -1}
+	switch f {
+	case LogFormatSyslog:
+		m := syslogRegexp.FindStringSubmatchIndex(line)
+		if m == nil {
+			return l, false
+		}
+		l.TimestampStart, l.TimestampEnd = m[4], m[5]
+		if m[2] >= 0 {
+			l.setSyslogPriority(line, m[2], m[3])
+		} else {
+			l.findLevelWord(line, m[1])
+		}
+	case LogFormatSyslog5424:
+		m := syslog5424Regexp.FindStringSubmatchIndex(line)
+		if m == nil {
+			return l, false
+		}
+		l.TimestampStart, l.TimestampEnd = m[4], m[5]
+		l.setSyslogPriority(line, m[2], m[3])
+	case LogFormatJournald:
+		m := journaldRegexp.FindStringSubmatchIndex(line)
+		if m == nil {
+			return l, false
+		}
+		switch line[m[2]:m[3]] {
+		case "__REALTIME_TIMESTAMP", "SYSLOG_TIMESTAMP":
+			l.TimestampStart, l.TimestampEnd = m[4], m[5]
+		case "PRIORITY":
+			l.setSyslogPriority(line, m[4], m[5])
+		}
+	case LogFormatCombined:
+		m := combinedRegexp.FindStringSubmatchIndex(line)
+		if m == nil {
+			return l, false
+		}
+		l.TimestampStart, l.TimestampEnd = m[2], m[3]
+		l.LevelStart, l.LevelEnd = m[4], m[5]
+		switch line[m[4]] {
+		case '5':
+			l.Level = LogLevelError
+		case '4':
+			l.Level = LogLevelWarning
+		default:
+			l.Level = LogLevelInfo
+		}
+	case LogFormatKlog:
+		m := klogRegexp.FindStringSubmatchIndex(line)
+		if m == nil {
+			return l, false
+		}
+		l.LevelStart, l.LevelEnd = m[2], m[3]
+		l.TimestampStart, l.TimestampEnd = m[4], m[5]
+		l.Level = klogLevels[line[m[2]]]
+	case LogFormatPython:
+		if m := pythonRegexp.FindStringSubmatchIndex(line); m != nil {
+			l.TimestampStart, l.TimestampEnd = m[2], m[3]
+			l.setLevel(line, m[4], m[5])
+		} else if m := pythonBasicRegexp.FindStringSubmatchIndex(line); m != nil {
+			l.setLevel(line, m[2], m[3])
+		} else {
+			return l, false
+		}
+	case LogFormatGo:
+		m := goLogRegexp.FindStringSubmatchIndex(line)
+		if m == nil {
+			return l, false
+		}
+		l.TimestampStart, l.TimestampEnd = m[2], m[3]
+		l.findLevelWord(line, m[1])
+	case LogFormatJSON:
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") || !json.Valid([]byte(trimmed)) {
+			return l, false
+		}
+		if m := jsonLevelRegexp.FindStringSubmatchIndex(line); m != nil {
+			l.setLevel(line, m[2], m[3])
+		}
+		if m := jsonTimeRegexp.FindStringSubmatchIndex(line); m != nil {
+			if m[2] >= 0 {
+				l.TimestampStart, l.TimestampEnd = m[2], m[3]
+			} else {
+				l.TimestampStart, l.TimestampEnd = m[4], m[5]
+			}
+		}
+		if l.LevelStart < 0 && l.TimestampStart < 0 {
+			// Not a log entry, just JSON
+			return l, false
+		}
+	case LogFormatLogfmt:
+		pairs := logfmtRegexp.FindAllStringSubmatchIndex(line, -1)
+		if len(pairs) < 2 || pairs[0][0] != 0 {
+			return l, false
+		}
+		known := false
+		for _, m := range pairs {
+			start, end := m[4], m[5]
+			if strings.HasPrefix(line[start:end], `"`) {
+				start, end = start+1, end-1
+			}
+			switch line[m[2]:m[3]] {
+			case "level", "lvl", "severity":
+				l.setLevel(line, start, end)
+				known = true
+			case "ts", "time", "timestamp", "t":
+				l.TimestampStart, l.TimestampEnd = start, end
+				known = true
+			case "msg", "message":
+				known = true
+			}
+		}
+		if !known {
+			return l, false
+		}
+	default:
+		return l, false
+	}
+	return l, true
+}
+
+// setLevel sets the level from the level name in line[start:end]
+func (l *LogLine) setLevel(line string, start, end int) {
+	l.Level = parseLogLevel(line[start:end])
+	l.LevelStart, l.LevelEnd = start, end
+}
+
+// setSyslogPriority sets the level from the syslog priority number in line[start:end],
+// where the lowest three bits are the severity
+func (l *LogLine) setSyslogPriority(line string, start, end int) {
+	priority, err := strconv.Atoi(line[start:end])
+	if err != nil {
+		return
+	}
+	l.LevelStart, l.LevelEnd = start, end
+	switch priority % 8 {
+	case 0, 1, 2: // emergency, alert, critical
+		l.Level = LogLevelCritical
+	case 3:
+		l.Level = LogLevelError
+	case 4:
+		l.Level = LogLevelWarning
+	case 5:
+		l.Level = LogLevelNotice
+	case 6:
+		l.Level = LogLevelInfo
+	case 7:
+		l.Level = LogLevelDebug
+	}
+}
+
+// findLevelWord looks for a level word, like ERROR or [warn], in the line, from the given offset
+func (l *LogLine) findLevelWord(line string, offset int) {
+	l.LevelStart, l.LevelEnd = -1, -1
+	m := levelWordRegexp.FindStringSubmatchIndex(line[offset:])
+	if m == nil {
+		return
+	}
+	start, end := m[2], m[3]
+	if start < 0 {
+		start, end = m[4], m[5]
+	}
+	l.setLevel(line, offset+start, offset+end)
+}
+
+// parseLogLevel returns the LogLevel for the given level name, like "WARN" or "error"
+func parseLogLevel(name string) LogLevel {
+	switch strings.ToLower(name) {
+	case "trace":
+		return LogLevelTrace
+	case "debug", "dbg":
+		return LogLevelDebug
+	case "info", "information", "informational":
+		return LogLevelInfo
+	case "notice":
+		return LogLevelNotice
+	case "warn", "warning":
+		return LogLevelWarning
+	case "error", "err":
+		return LogLevelError
+	case "crit", "critical", "fatal", "panic", "dpanic", "alert", "emerg", "emergency":
+		return LogLevelCritical
+	}
+	return LogLevelNone
+}
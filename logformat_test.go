@@ -0,0 +1,120 @@
+package mode
+
+import "testing"
+
+func TestDetectLogFormat(t *testing.T) {
+	formats := []struct {
+		data   string
+		format LogFormat
+	}{
+		{"Jan  2 15:04:05 web sshd[42]: Accepted publickey for root\nJan  2 15:04:06 web kernel: eth0: link up\n", LogFormatSyslog},
+		{"<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8\n", LogFormatSyslog},
+		{"<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - An application event\n", LogFormatSyslog5424},
+		{"__CURSOR=s=1;i=2\n__REALTIME_TIMESTAMP=1700000000000000\nPRIORITY=6\nMESSAGE=Started\n\n__CURSOR=s=1;i=3\n", LogFormatJournald},
+		{"127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] \"GET /apache_pb.gif HTTP/1.0\" 200 2326 \"http://www.example.com/\" \"Mozilla/4.08\"\n", LogFormatCombined},
+		{"I0102 15:04:05.123456    1 main.go:12] Starting\nE0102 15:04:06.000001    1 server.go:99] Failed\n", LogFormatKlog},
+		{"2024-01-02 15:04:05,123 - app - INFO - Started\nTraceback (most recent call last):\n  File \"app.py\", line 1\n2024-01-02 15:04:06,000 - app - ERROR - Failed\n", LogFormatPython},
+		{"WARNING:root:Disk is almost full\nINFO:app.db:Connected\n", LogFormatPython},
+		{"2009/11/10 23:00:00 Hello, log file!\n2009/11/10 23:00:01 ERROR something failed\n", LogFormatGo},
+		{"{\"time\":\"2024-01-02T15:04:05Z\",\"level\":\"INFO\",\"msg\":\"started\"}\n", LogFormatJSON},
+		{"ts=2024-01-02T15:04:05Z level=info msg=\"server started\" port=8080\n", LogFormatLogfmt},
+		{"{\"a\": 1}\n{\"a\": 2}\n", LogFormatNone},
+		{"name = app\nport = 8080\n", LogFormatNone},
+		{"FOO=bar\nBAZ=qux\n", LogFormatNone},
+		{"Hello there\nJan  2 15:04:05 web sshd[42]: Accepted\n", LogFormatNone},
+		{"", LogFormatNone},
+	}
+	for _, f := range formats {
+		if format := DetectLogFormat([]byte(f.data)); format != f.format {
+			t.Errorf("Expected %s for %q, got %s", f.format, f.data, format)
+		}
+	}
+	for f := LogFormatNone; f < lastLogFormat; f++ {
+		if f.String() == "?" {
+			t.Errorf("LogFormat %d has no string", f)
+		}
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	lines := []struct {
+		format    LogFormat
+		line      string
+		timestamp string
+		level     LogLevel
+		levelText string
+	}{
+		{LogFormatSyslog, "Jan  2 15:04:05 web app[1]: ERROR disk full", "Jan  2 15:04:05", LogLevelError, "ERROR"},
+		{LogFormatSyslog, "<11>Jan  2 15:04:05 web app: failed", "Jan  2 15:04:05", LogLevelError, "11"},
+		{LogFormatSyslog5424, "<165>1 2003-10-11T22:14:15.003Z host app - ID47 - event", "2003-10-11T22:14:15.003Z", LogLevelNotice, "165"},
+		{LogFormatJournald, "PRIORITY=4", "", LogLevelWarning, "4"},
+		{LogFormatJournald, "__REALTIME_TIMESTAMP=1700000000000000", "1700000000000000", LogLevelNone, ""},
+		{LogFormatCombined, "10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] \"GET / HTTP/1.1\" 503 12", "10/Oct/2000:13:55:36 -0700", LogLevelError, "503"},
+		{LogFormatKlog, "W0102 15:04:05.123456 1 main.go:12] Slow", "0102 15:04:05.123456", LogLevelWarning, "W"},
+		{LogFormatPython, "2024-01-02 15:04:05,123 - app - CRITICAL - Down", "2024-01-02 15:04:05,123", LogLevelCritical, "CRITICAL"},
+		{LogFormatPython, "DEBUG:root:x", "", LogLevelDebug, "DEBUG"},
+		{LogFormatGo, "2009/11/10 23:00:00 [warn] slow request", "2009/11/10 23:00:00", LogLevelWarning, "warn"},
+		{LogFormatJSON, "{\"ts\":1700000000.5,\"level\":\"error\",\"msg\":\"x\"}", "1700000000.5", LogLevelError, "error"},
+		{LogFormatLogfmt, "time=\"2024-01-02 15:04:05\" lvl=dbug msg=x", "2024-01-02 15:04:05", LogLevelNone, "dbug"},
+		{LogFormatLogfmt, "t=2024-01-02T15:04:05Z level=warn msg=x", "2024-01-02T15:04:05Z", LogLevelWarning, "warn"},
+		{LogFormatPython, "    raise ValueError(\"FATAL problem\")", "", LogLevelCritical, "FATAL"},
+		{LogFormatGo, "    at main.go:12", "", LogLevelNone, ""},
+	}
+	for _, l := range lines {
+		parsed := l.format.ParseLine(l.line)
+		var timestamp, levelText string
+		if parsed.TimestampStart >= 0 {
+			timestamp = l.line[parsed.TimestampStart:parsed.TimestampEnd]
+		}
+		if parsed.LevelStart >= 0 {
+			levelText = l.line[parsed.LevelStart:parsed.LevelEnd]
+		}
+		if timestamp != l.timestamp || parsed.Level != l.level || levelText != l.levelText {
+			t.Errorf("Expected %q, %s and %q for %q, got %q, %s and %q", l.timestamp, l.level, l.levelText, l.line, timestamp, parsed.Level, levelText)
+		}
+	}
+}
+
+func TestDetectLogContents(t *testing.T) {
+	contents := map[string]Mode{
+		"Jan  2 15:04:05 web sshd[42]: Accepted publickey for root\n":                                                                                   Log,
+		"2024-01-02 15:04:05,123 - app - INFO - Started\n":                                                                                              Log,
+		"ts=2024-01-02T15:04:05Z level=info msg=started\n":                                                                                              Log,
+		"{\"time\":\"2024-01-02T15:04:05Z\",\"level\":\"INFO\",\"msg\":\"started\"}\n":                                                                  JSON,
+		"ts=2024-01-02T15:04:05Z level=info msg=a\nts=2024-01-02T15:04:06Z level=warn msg=b\nts=2024-01-02T15:04:07Z level=info msg=c\n":                Log,
+		"2024-01-02 15:04:05,123 - app - INFO - Started\n2024-01-02 15:04:06,123 - app - INFO - Next\n2024-01-02 15:04:07,123 - app - ERROR - Failed\n": Log,
+		"#!/usr/bin/env node\nJan  2 15:04:05 web sshd[42]: Accepted publickey for root\n":                                                              Blank,
+	}
+	for data, expected := range contents {
+		if m := SimpleDetect(data); m != expected {
+			t.Errorf("Expected %s for %q, got %s", Mode(expected), data, m)
+		}
+	}
+	if m := DetectFile("output.txt", []byte("I0102 15:04:05.123456 1 main.go:12] Starting\n")); m != Log {
+		t.Errorf("Expected Log for output.txt, got %s", m)
+	}
+	for _, name := range []string{"app.log.1", "app.log.2024-01-02", "server.log.2.gz", "access_log", "/var/log/messages", "syslog", "/var/log/messages.1", "syslog.1", "syslog.2.gz", "dmesg.0", "error_log-20240102", "access_log.2024-01-02"} {
+		if m := Detect(name); m != Log {
+			t.Errorf("Expected Log for %s, got %s", name, m)
+		}
+	}
+	for _, name := range []string{"catalog.tar", "syslog.conf", "messages.po", "syslog-ng.conf", "messages.", "messages.-"} {
+		if m := Detect(name); m == Log {
+			t.Errorf("Expected %s not to be a log file", name)
+		}
+	}
+}
+
+func TestDetectLogFormatLimit(t *testing.T) {
+	data := []byte("Jan  2 15:04:05 web sshd[42]: Accepted publickey for root\nJan  2 15:04:06 web kernel: eth0: link up\n")
+	defer func(limit int) { CSVSniffLimit = limit }(CSVSniffLimit)
+	CSVSniffLimit = 8
+	if m := SimpleDetectBytes(data); m != Log {
+		t.Errorf("Expected the CSV limit not to change log detection, got %s", m)
+	}
+	defer func(limit int) { LogSniffLimit = limit }(LogSniffLimit)
+	LogSniffLimit = 8
+	if f := DetectLogFormat(data); f != LogFormatNone {
+		t.Errorf("Expected only the first %d bytes to be looked at, got %s", LogSniffLimit, f)
+	}
+}
@@ -0,0 +1,117 @@
+package mode
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// magicNumber is a known byte sequence at a fixed offset, that identifies a binary file format
+type magicNumber struct {
+	offset int
+	magic  string
+	mode   Mode
+	mime   string
+	check  func(data []byte) bool // an optional extra check, for short or ambiguous magic numbers
+}
+
+// magicNumbers is the table of binary file formats that DetectMagic knows about.
+// ZIP files are looked into by detectZIP, to find office documents.
+var magicNumbers = []magicNumber{
+	{0, "%PDF-", PDF, "application/pdf", nil},
+	{0, "\x89PNG\r\n\x1a\n", Image, "image/png", nil},
+	{0, "\xff\xd8\xff", Image, "image/jpeg", nil},
+	{0, "GIF87a", Image, "image/gif", nil},
+	{0, "GIF89a", Image, "image/gif", nil},
+	{0, "RIFF", Image, "image/webp", func(data []byte) bool { return len(data) >= 12 && string(data[8:12]) == "WEBP" }},
+	{0, "II*\x00", Image, "image/tiff", nil},
+	{0, "MM\x00*", Image, "image/tiff", nil},
+	{0, "\x1f\x8b", Archive, "application/gzip", nil},
+	{0, "BZh", Archive, "application/x-bzip2", func(data []byte) bool { return len(data) >= 10 && string(data[4:10]) == "1AY&SY" }},
+	{0, "\xfd7zXZ\x00", Archive, "application/x-xz", nil},
+	{0, "(\xb5/\xfd", Archive, "application/zstd", nil},
+	{0, "\x04\x22\x4d\x18", Archive, "application/x-lz4", nil},
+	{0, "7z\xbc\xaf\x27\x1c", Archive, "application/x-7z-compressed", nil},
+	{0, "Rar!\x1a\x07", Archive, "application/vnd.rar", nil},
+	{257, "ustar", Archive, "application/x-tar", nil},
+	{0, "\x7fELF", Executable, "application/x-elf", nil},
+	{0, "\xfe\xed\xfa\xce", Executable, "application/x-mach-binary", nil},
+	{0, "\xfe\xed\xfa\xcf", Executable, "application/x-mach-binary", nil},
+	{0, "\xce\xfa\xed\xfe", Executable, "application/x-mach-binary", nil},
+	{0, "\xcf\xfa\xed\xfe", Executable, "application/x-mach-binary", nil},
+	{0, "\xca\xfe\xba\xbe", Executable, "application/x-mach-binary", func(data []byte) bool {
+		// Universal Mach-O binaries have a small number of architectures here, Java classes have a version number
+		return len(data) >= 8 && binary.BigEndian.Uint32(data[4:8]) < 20
+	}},
+	{0, "\xca\xfe\xba\xbe", Executable, "application/java-vm", nil},
+	{0, "MZ", Executable, "application/vnd.microsoft.portable-executable", isPortableExecutable},
+	{0, "\x00asm", WebAssembly, "application/wasm", nil},
+	{0, "SQLite format 3\x00", Binary, "application/vnd.sqlite3", nil},
+}
+
+// isPortableExecutable checks if the given data, starting with "MZ", has a PE header
+func isPortableExecutable(data []byte) bool {
+	if len(data) < 0x40 {
+		return false
+	}
+	offset := int(binary.LittleEndian.Uint32(data[0x3c:0x40]))
+	return offset > 0 && offset+4 <= len(data) && string(data[offset:offset+4]) == "PE\x00\x00"
+}
+
+// DetectMagic looks for the magic number of a known binary file format at the start of the given data.
+// Returns the Mode and the MIME type, or Blank and an empty string if no known format is found.
+// The first few kilobytes of a file are enough for recognizing ZIP-based office documents.
+func DetectMagic(data []byte) (Mode, string) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return detectZIP(data)
+	}
+	for _, mn := range magicNumbers {
+		if len(data) >= mn.offset+len(mn.magic) && string(data[mn.offset:mn.offset+len(mn.magic)]) == mn.magic && (mn.check == nil || mn.check(data)) {
+			return mn.mode, mn.mime
+		}
+	}
+	return Blank, ""
+}
+
+// detectZIP looks at the file entries at the start of the given ZIP data, to find OpenDocument
+// and Office Open XML documents. Returns Archive if it looks like a regular ZIP file.
+func detectZIP(data []byte) (Mode, string) {
+	const odfPrefix = "mimetypeapplication/vnd.oasis.opendocument"
+	if i := bytes.Index(data, []byte(odfPrefix)); i >= 0 && i < 512 {
+		mime := data[i+len("mimetype"):]
+		if end := bytes.IndexFunc(mime, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '/' || r == '-' || r == '+')
+		}); end >= 0 {
+			mime = mime[:end]
+		}
+		return LibreOffice, string(mime)
+	}
+	if bytes.Contains(data, []byte("[Content_Types].xml")) {
+		switch {
+		case bytes.Contains(data, []byte("word/")):
+			return DOCX, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		case bytes.Contains(data, []byte("xl/")):
+			return XLSX, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		case bytes.Contains(data, []byte("ppt/")):
+			return PPTX, "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+		}
+	}
+	return Archive, "application/zip"
+}
+
+// binaryFormat checks if the given mode is for a binary file format, or for a format that an editor may still handle
+func binaryFormat(m Mode) bool {
+	switch m {
+	case Abiword, Archive, Binary, DOCX, Executable, Image, LibreOffice, PDF, PPTX, WebAssembly, XLSX:
+		return true
+	}
+	return false
+}
+
+// detectBinary tries to find a Mode for the given binary data.
+// Returns Binary if the format is not recognized.
+func detectBinary(data []byte) Mode {
+	if m, _ := DetectMagic(data); m != Blank {
+		return m
+	}
+	return Binary
+}
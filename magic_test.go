@@ -0,0 +1,55 @@
+package mode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectMagic(t *testing.T) {
+	pe := make([]byte, 0x84)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x80
+	copy(pe[0x80:], "PE\x00\x00")
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar")
+	tests := []struct {
+		data string
+		m    Mode
+		mime string
+	}{
+		{"%PDF-1.7\n", PDF, "application/pdf"},
+		{"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", Image, "image/png"},
+		{"\xff\xd8\xff\xe0\x00\x10JFIF", Image, "image/jpeg"},
+		{"GIF89a\x01\x00", Image, "image/gif"},
+		{"RIFF\x00\x00\x00\x00WEBPVP8 ", Image, "image/webp"},
+		{"\x1f\x8b\x08\x00", Archive, "application/gzip"},
+		{"\xfd7zXZ\x00\x00", Archive, "application/x-xz"},
+		{"(\xb5/\xfd\x00", Archive, "application/zstd"},
+		{string(tar), Archive, "application/x-tar"},
+		{"\x7fELF\x02\x01\x01", Executable, "application/x-elf"},
+		{"\xcf\xfa\xed\xfe\x07\x00\x00\x01", Executable, "application/x-mach-binary"},
+		{"\xca\xfe\xba\xbe\x00\x00\x00\x34", Executable, "application/java-vm"},
+		{string(pe), Executable, "application/vnd.microsoft.portable-executable"},
+		{"\x00asm\x01\x00\x00\x00", WebAssembly, "application/wasm"},
+		{"PK\x03\x04\x14\x00[Content_Types].xml\x00PK\x03\x04word/document.xml", DOCX, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"PK\x03\x04\x14\x00[Content_Types].xml\x00PK\x03\x04xl/workbook.xml", XLSX, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"PK\x03\x04\x14\x00[Content_Types].xml\x00PK\x03\x04ppt/presentation.xml", PPTX, "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		{"PK\x03\x04\x00\x00mimetypeapplication/vnd.oasis.opendocument.spreadsheetPK", LibreOffice, "application/vnd.oasis.opendocument.spreadsheet"},
+		{"PK\x03\x04\x14\x00hello.txt", Archive, "application/zip"},
+		{"MZ is not enough", Blank, ""},
+		{"BZh is not enough", Blank, ""},
+		{"hello", Blank, ""},
+	}
+	for _, test := range tests {
+		if m, mime := DetectMagic([]byte(test.data)); m != test.m || mime != test.mime {
+			t.Errorf("Expected %s (%s), got %s (%s) for %q", Mode(test.m), test.mime, m, mime, test.data)
+		}
+	}
+	// Misnamed and extensionless files
+	if m := DetectFile("README", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")); m != Image {
+		t.Errorf("Expected Image, got %s", m)
+	}
+	if m := DetectFile("report.docx", []byte("PK\x03\x04"+strings.Repeat("\x00", 10))); m != DOCX {
+		t.Errorf("Expected DOCX, got %s", m)
+	}
+}
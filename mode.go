@@ -1,6 +1,10 @@
 // Package mode tries to find the correct editor mode, given a filename and/or file data
 package mode
 
+import (
+	"strings"
+)
+
 // Mode is a per-filetype mode, like for Markdown
 type Mode int
 
@@ -13,6 +17,7 @@ const (
 	Agda                  // Agda
 	Algol68               // ALGOL 68
 	Amber                 // Amber templates
+	Archive               // ZIP, tar and compressed archives
 	Arduino               // Arduino
 	ASCIIDoc              // ASCII doc
 	Assembly              // Assembly
@@ -21,9 +26,11 @@ const (
 	Battlestar            // Battlestar
 	Bazel                 // Bazel and Starlark
 	Beef                  // Beef
+	Binary                // Binary files that are not text
 	Blueprint             // GNOME Blueprint
 	C                     // C
 	C3                    // C3
+	Caddyfile             // Caddy web server configuration
 	CMake                 // CMake files
 	CS                    // C#
 	CSound                // CSound // music
@@ -31,6 +38,7 @@ const (
 	CSV                   // CSV and TSV data files
 	Chuck                 // Chuck // music
 	Clojure               // Clojure
+	CodeOwners            // CODEOWNERS files, with patterns and owners
 	COBOL                 // COBOL
 	Config                // Config files like ini, bp and various service/socket files
 	Cpp                   // C++
@@ -43,9 +51,11 @@ const (
 	Dingo                 // Dingo
 	Docker                // For Dockerfiles
 	Email                 // For using o with ie. Mutt
+	ERB                   // Embedded Ruby templates, like index.html.erb
 	Elixir                // Elixir
 	Elm                   // Elm
 	Erlang                // Erlang
+	Executable            // ELF, Mach-O and PE executables and libraries
 	Faust                 // Faust
 	Fortran77             // Fortran 77
 	Fortran90             // Fortran 90
@@ -59,6 +69,7 @@ const (
 	GoMod                 // go.mod files
 	GoAssembly            // Go-style Assembly
 	Gradle                // Gradle
+	Groovy                // Groovy and Jenkinsfiles
 	HCL                   // HashiCorp Configuration Language (Terraform)
 	Haxe                  // Haxe: .hx and .hxml files
 	HIDL                  // Android-related: Hardware Abstraction Layer Interface Definition Language
@@ -67,6 +78,7 @@ const (
 	Hare                  // Hare
 	Haskell               // Haskell
 	Ignore                // .gitignore and .ignore files
+	Image                 // PNG, JPEG, GIF, WebP and TIFF images
 	Ini                   // INI Configuration
 	Inko                  // Inko
 	Ivy                   // Ivy
@@ -75,6 +87,7 @@ const (
 	Java                  // Java
 	JavaScript            // JavaScript
 	Janet                 // Janet
+	Julia                 // Julia
 	Just                  // Just
 	Koka                  // Koka
 	Kotlin                // Kotlin
@@ -100,10 +113,13 @@ const (
 	Odin                  // Odin
 	Ollama                // For Modelfiles
 	Perl                  // Perl
+	PDF                   // PDF documents
 	PHP                   // PHP
 	Pkl                   // Pkl configuration language
 	PolicyLanguage        // SE Linux configuration files
 	POV                   // POV-Ray raytracer
+	PPTX                  // PowerPoint presentations
+	Procfile              // Procfiles, with process types and commands
 	Prolog                // Prolog
 	Protobuf              // Protocol Buffers
 	Python                // Python
@@ -133,17 +149,19 @@ const (
 	TypeScript            // TypeScript
 	V                     // V programming language
 	Vim                   // Vim or NeoVim configuration, or .vim scripts
+	WebAssembly           // WebAssembly modules
 	WGSL                  // WebGPU Shading Language
 	WordGrinder           // WordGrinder
 	XML                   // XML
+	XLSX                  // Excel spreadsheets
 	YAML                  // YAML
 	Zig                   // Zig
+	lastMode              // lastMode is not a mode, it is used for iterating over all modes
 )
 
 // String will return a short lowercase string representing the given editor mode
 func (mode Mode) String() string {
 	// TODO: Sort the cases alphabetically
-	// TODO: Add a test that makes sure every mode has a string
 	switch mode {
 	case ABC:
 		return "ABC"
@@ -163,6 +181,8 @@ func (mode Mode) String() string {
 		return "Arduino"
 	case ASCIIDoc:
 		return "ASCII Doc"
+	case Archive:
+		return "Archive"
 	case Assembly:
 		return "Assembly"
 	case Basic:
@@ -175,6 +195,8 @@ func (mode Mode) String() string {
 		return "Bazel"
 	case Beef:
 		return "Beef"
+	case Binary:
+		return "Binary"
 	case Blueprint:
 		return "Blueprint"
 	case Blank:
@@ -183,8 +205,12 @@ func (mode Mode) String() string {
 		return "C"
 	case C3:
 		return "C3"
+	case Caddyfile:
+		return "Caddyfile"
 	case Clojure:
 		return "Clojure"
+	case CodeOwners:
+		return "CODEOWNERS"
 	case Chuck:
 		return "Chuck"
 	case CMake:
@@ -225,8 +251,12 @@ func (mode Mode) String() string {
 		return "Elm"
 	case Email:
 		return "E-mail"
+	case ERB:
+		return "ERB"
 	case Erlang:
 		return "Erlang"
+	case Executable:
+		return "Executable"
 	case Faust:
 		return "Faust"
 	case Fortran77:
@@ -253,6 +283,8 @@ func (mode Mode) String() string {
 		return "Go Module"
 	case Gradle:
 		return "Gradle"
+	case Groovy:
+		return "Groovy"
 	case Hare:
 		return "Hare"
 	case Haskell:
@@ -269,6 +301,8 @@ func (mode Mode) String() string {
 		return "HTTP Tests"
 	case Ignore:
 		return "Ignore"
+	case Image:
+		return "Image"
 	case Ini:
 		return "INI Configuration"
 	case Inko:
@@ -285,6 +319,8 @@ func (mode Mode) String() string {
 		return "JSON"
 	case Janet:
 		return "Janet"
+	case Julia:
+		return "Julia"
 	case Just:
 		return "Just"
 	case Koka:
@@ -335,6 +371,8 @@ func (mode Mode) String() string {
 		return "Odin"
 	case Perl:
 		return "Perl"
+	case PDF:
+		return "PDF"
 	case PHP:
 		return "PHP"
 	case Pkl:
@@ -343,6 +381,10 @@ func (mode Mode) String() string {
 		return "SELinux"
 	case POV:
 		return "POV-Ray"
+	case PPTX:
+		return "PPTX"
+	case Procfile:
+		return "Procfile"
 	case Prolog:
 		return "Prolog"
 	case Protobuf:
@@ -399,12 +441,16 @@ func (mode Mode) String() string {
 		return "TypeScript"
 	case Vim:
 		return "ViM"
+	case WebAssembly:
+		return "WebAssembly"
 	case WGSL:
 		return "WGSL"
 	case WordGrinder:
 		return "WordGrinder"
 	case V:
 		return "V"
+	case XLSX:
+		return "XLSX"
 	case XML:
 		return "XML"
 	case YAML:
@@ -415,3 +461,89 @@ func (mode Mode) String() string {
 		return "?"
 	}
 }
+
+// modeAliases are alternative names for modes, such as the language names used by
+// GitHub Linguist, normalized with normalizeModeName
+var modeAliases = map[string]Mode{
+	"apkbuild":         Shell,
+	"asm":              Assembly,
+	"bash":             Shell,
+	"batchfile":        Bat,
+	"c++":              Cpp,
+	"commonlisp":       Lisp,
+	"config":           Config,
+	"cpp":              Cpp,
+	"csharp":           CS,
+	"delphi":           ObjectPascal,
+	"diff":             Diff,
+	"dockerfile":       Docker,
+	"emacslisp":        Lisp,
+	"fortran":          Fortran90,
+	"fortranfreeform":  Fortran90,
+	"gitcommit":        Git,
+	"gitignore":        Ignore,
+	"glsl":             Shader,
+	"golang":           Go,
+	"html+erb":         ERB,
+	"hlsl":             Shader,
+	"ignorelist":       Ignore,
+	"ini":              Ini,
+	"js":               JavaScript,
+	"json5":            JSON,
+	"jsonc":            JSON,
+	"jsonlines":        JSON,
+	"jsonwithcomments": JSON,
+	"jsx":              JavaScript,
+	"jupyternotebook":  JSON,
+	"makefile":         Make,
+	"md":               Markdown,
+	"ndjson":           JSON,
+	"objectivec":       ObjC,
+	"pascal":           ObjectPascal,
+	"patch":            Diff,
+	"plaintext":        Text,
+	"povraysdl":        POV,
+	"protocolbuffer":   Protobuf,
+	"py":               Python,
+	"rb":               Ruby,
+	"richtextformat":   RTF,
+	"roff":             Nroff,
+	"rs":               Rust,
+	"rst":              ReStructured,
+	"selinuxpolicy":    PolicyLanguage,
+	"sh":               Shell,
+	"terraform":        HCL,
+	"ts":               TypeScript,
+	"tsx":              TypeScript,
+	"txt":              Text,
+	"unixassembly":     Assembly,
+	"viml":             Vim,
+	"vimscript":        Vim,
+	"yml":              YAML,
+	"zsh":              Shell,
+}
+
+// normalizeModeName returns the given mode name in lowercase, without spaces, dashes and underscores
+func normalizeModeName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// ParseMode returns the Mode for the given language name, as returned by Mode.String,
+// or as used by GitHub Linguist. The lookup is case-insensitive and ignores spaces,
+// dashes and underscores, so both "Emacs Lisp" and "emacs-lisp" work.
+// Returns false if no mode could be found.
+func ParseMode(name string) (Mode, bool) {
+	normalized := normalizeModeName(name)
+	if normalized == "" {
+		return Blank, false
+	}
+	for m := Mode(Blank + 1); m < lastMode; m++ {
+		if normalizeModeName(m.String()) == normalized {
+			return m, true
+		}
+	}
+	if m, ok := modeAliases[normalized]; ok {
+		return m, true
+	}
+	return Blank, false
+}
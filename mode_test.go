@@ -0,0 +1,40 @@
+package mode
+
+import (
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	for m := Mode(Blank); m < lastMode; m++ {
+		if m.String() == "?" {
+			t.Errorf("Mode %d has no string", m)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	names := map[string]Mode{
+		"Go":          Go,
+		"go":          Go,
+		"C++":         Cpp,
+		"C#":          CS,
+		"Objective-C": ObjC,
+		"Emacs Lisp":  Lisp,
+		"emacs-lisp":  Lisp,
+		"Shell":       Shell,
+		"Dockerfile":  Docker,
+		"ocaml":       OCaml,
+		"PHP":         PHP,
+	}
+	for name, expected := range names {
+		if m, ok := ParseMode(name); !ok || m != expected {
+			t.Errorf("Expected %s for %q, got %s", Mode(expected), name, m)
+		}
+	}
+	if _, ok := ParseMode("no such language"); ok {
+		t.Fail()
+	}
+	if _, ok := ParseMode(""); ok {
+		t.Fail()
+	}
+}
@@ -0,0 +1,294 @@
+package mode
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Notebook is the result of inspecting a Jupyter notebook
+type Notebook struct {
+	Mode     Mode           // the Mode of the kernel language, like Python, or Blank if it is not known
+	Language string         // the kernel language, as given in the notebook metadata, like "python"
+	Cells    []NotebookCell // the cells, in order
+}
+
+// NotebookCell is a cell in a Jupyter notebook
+type NotebookCell struct {
+	Type string // "code", "markdown" or "raw"
+	Mode Mode   // the Mode of the contents of the cell
+}
+
+// notebookCell is a cell, while the notebook is being read
+type notebookCell struct {
+	cellType     string
+	firstLine    string // the first line of the source, for finding cell magics like %%bash
+	languageID   string // from the VS Code cell metadata
+	rawMimeType  string // from the raw cell metadata
+	languageName string // from the cell metadata in older notebooks
+}
+
+// cellMagics maps Jupyter cell magics to modes
+var cellMagics = map[string]Mode{
+	"%%bash":       Shell,
+	"%%html":       HTML,
+	"%%javascript": JavaScript,
+	"%%js":         JavaScript,
+	"%%markdown":   Markdown,
+	"%%perl":       Perl,
+	"%%ruby":       Ruby,
+	"%%sh":         Shell,
+	"%%sql":        SQL,
+	"%%svg":        XML,
+}
+
+// rawMimeTypes maps the MIME types of raw cells to modes
+var rawMimeTypes = map[string]Mode{
+	"text/html":                HTML,
+	"text/markdown":            Markdown,
+	"text/restructuredtext":    ReStructured,
+	"text/x-python":            Python,
+	"text/x-rst":               ReStructured,
+	"application/x-ipynb+json": JSON,
+}
+
+// InspectNotebook reads a Jupyter notebook and finds the kernel language and the Mode of each cell.
+// The notebook is read as a stream of JSON tokens, so that cell outputs, which can contain large
+// images, are skipped instead of being kept in memory.
+func InspectNotebook(r io.Reader) (*Notebook, error) {
+	var (
+		dec          = json.NewDecoder(r)
+		cells        []notebookCell
+		language     string
+		kernelName   string
+		languageInfo string
+	)
+	err := readJSONObject(dec, func(key string) error {
+		switch key {
+		case "cells":
+			return readJSONArray(dec, func() error {
+				var cell notebookCell
+				err := readJSONObject(dec, func(key string) error {
+					switch key {
+					case "cell_type":
+						return readJSONString(dec, &cell.cellType)
+					case "source", "input":
+						return readSourceFirstLine(dec, &cell.firstLine)
+					case "language":
+						return readJSONString(dec, &cell.languageName)
+					case "metadata":
+						return readJSONObject(dec, func(key string) error {
+							switch key {
+							case "raw_mimetype", "format":
+								return readJSONString(dec, &cell.rawMimeType)
+							case "vscode":
+								return readJSONObject(dec, func(key string) error {
+									if key == "languageId" {
+										return readJSONString(dec, &cell.languageID)
+									}
+									return skipJSONValue(dec)
+								})
+							}
+							return skipJSONValue(dec)
+						})
+					}
+					return skipJSONValue(dec)
+				})
+				cells = append(cells, cell)
+				return err
+			})
+		case "metadata":
+			return readJSONObject(dec, func(key string) error {
+				switch key {
+				case "kernelspec":
+					return readJSONObject(dec, func(key string) error {
+						switch key {
+						case "language":
+							return readJSONString(dec, &language)
+						case "name":
+							return readJSONString(dec, &kernelName)
+						}
+						return skipJSONValue(dec)
+					})
+				case "language_info":
+					return readJSONObject(dec, func(key string) error {
+						if key == "name" {
+							return readJSONString(dec, &languageInfo)
+						}
+						return skipJSONValue(dec)
+					})
+				}
+				return skipJSONValue(dec)
+			})
+		}
+		return skipJSONValue(dec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	nb := &Notebook{Language: languageInfo}
+	if nb.Language == "" {
+		nb.Language = language
+	}
+	if nb.Language == "" {
+		// Kernel names are often like "python3" or "ir"
+		nb.Language = strings.TrimRight(kernelName, "0123456789.")
+	}
+	nb.Mode, _ = ParseMode(nb.Language)
+	for _, cell := range cells {
+		nb.Cells = append(nb.Cells, NotebookCell{Type: cell.cellType, Mode: cell.mode(nb.Mode)})
+	}
+	return nb, nil
+}
+
+// mode returns the Mode of the contents of the cell, where kernelMode is the Mode of the notebook
+func (cell *notebookCell) mode(kernelMode Mode) Mode {
+	switch cell.cellType {
+	case "markdown":
+		return Markdown
+	case "raw":
+		if m, ok := rawMimeTypes[cell.rawMimeType]; ok {
+			return m
+		}
+		return Text
+	}
+	if magic, _, _ := strings.Cut(strings.TrimSpace(cell.firstLine), " "); strings.HasPrefix(magic, "%%") {
+		if m, ok := cellMagics[magic]; ok {
+			return m
+		}
+	}
+	for _, name := range []string{cell.languageID, cell.languageName} {
+		if m, ok := ParseMode(name); ok {
+			return m
+		}
+	}
+	return kernelMode
+}
+
+// errUnexpectedJSON is returned when the notebook does not have the expected structure
+var errUnexpectedJSON = errors.New("unexpected JSON structure in notebook")
+
+// readJSONObject reads a JSON object and calls f for each key, where f must read or skip the value
+func readJSONObject(dec *json.Decoder, f func(key string) error) error {
+	if err := expectJSONDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return errUnexpectedJSON
+		}
+		if err := f(key); err != nil {
+			return err
+		}
+	}
+	return expectJSONDelim(dec, '}')
+}
+
+// readJSONArray reads a JSON array and calls f for each element, where f must read or skip the element
+func readJSONArray(dec *json.Decoder, f func() error) error {
+	if err := expectJSONDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return expectJSONDelim(dec, ']')
+}
+
+// expectJSONDelim reads a token and checks that it is the given delimiter
+func expectJSONDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return errUnexpectedJSON
+	}
+	return nil
+}
+
+// readJSONString reads a JSON value into s if it is a string, or skips it if it is not
+func readJSONString(dec *json.Decoder, s *string) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := t.(type) {
+	case string:
+		*s = v
+	case json.Delim:
+		return skipJSONRest(dec)
+	}
+	return nil
+}
+
+// readSourceFirstLine reads the source of a cell, which is a string or an array of lines,
+// and keeps the first line
+func readSourceFirstLine(dec *json.Decoder, firstLine *string) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := t.(type) {
+	case string:
+		*firstLine, _, _ = strings.Cut(v, "\n")
+	case json.Delim:
+		if v != '[' {
+			return skipJSONRest(dec)
+		}
+		for first := true; dec.More(); first = false {
+			t, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if s, ok := t.(string); ok && first {
+				*firstLine, _, _ = strings.Cut(s, "\n")
+			} else if _, ok := t.(json.Delim); ok {
+				if err := skipJSONRest(dec); err != nil {
+					return err
+				}
+			}
+		}
+		return expectJSONDelim(dec, ']')
+	}
+	return nil
+}
+
+// skipJSONValue reads and throws away the next JSON value
+func skipJSONValue(dec *json.Decoder) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if _, ok := t.(json.Delim); ok {
+		return skipJSONRest(dec)
+	}
+	return nil
+}
+
+// skipJSONRest reads and throws away the rest of an object or array, after the opening delimiter has been read
+func skipJSONRest(dec *json.Decoder) error {
+	for depth := 1; depth > 0; {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := t.(json.Delim); ok {
+			switch d {
+			case '{', '[':
+				depth++
+			default:
+				depth--
+			}
+		}
+	}
+	return nil
+}
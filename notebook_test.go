@@ -0,0 +1,107 @@
+package mode
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": ["# Analysis\n", "Some text"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "metadata": {},
+   "outputs": [
+    {
+     "data": {"image/png": "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="},
+     "output_type": "display_data",
+     "metadata": {"nested": [[1, 2], {"a": []}]}
+    }
+   ],
+   "source": ["import pandas as pd\n", "df = pd.DataFrame()"]
+  },
+  {
+   "cell_type": "code",
+   "metadata": {},
+   "outputs": [],
+   "source": "%%bash\nls -l"
+  },
+  {
+   "cell_type": "code",
+   "metadata": {"vscode": {"languageId": "sql"}},
+   "outputs": [],
+   "source": []
+  },
+  {
+   "cell_type": "raw",
+   "metadata": {"raw_mimetype": "text/restructuredtext"},
+   "source": ["Title\n", "====="]
+  },
+  {
+   "cell_type": "raw",
+   "metadata": {},
+   "source": []
+  }
+ ],
+ "metadata": {
+  "kernelspec": {"display_name": "Python 3", "language": "python", "name": "python3"},
+  "language_info": {"name": "python", "version": "3.12.0"}
+ },
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+func TestInspectNotebook(t *testing.T) {
+	nb, err := InspectNotebook(strings.NewReader(testNotebook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nb.Mode != Python || nb.Language != "python" {
+		t.Errorf("Expected a Python notebook, got %s (%q)", nb.Mode, nb.Language)
+	}
+	expected := []NotebookCell{
+		{"markdown", Markdown},
+		{"code", Python},
+		{"code", Shell},
+		{"code", SQL},
+		{"raw", ReStructured},
+		{"raw", Text},
+	}
+	if len(nb.Cells) != len(expected) {
+		t.Fatalf("Expected %d cells, got %d", len(expected), len(nb.Cells))
+	}
+	for i, cell := range nb.Cells {
+		if cell != expected[i] {
+			t.Errorf("Expected %s %s for cell %d, got %s %s", expected[i].Type, expected[i].Mode, i, cell.Type, cell.Mode)
+		}
+	}
+}
+
+func TestInspectNotebookKernels(t *testing.T) {
+	kernels := map[string]Mode{
+		`{"metadata": {"kernelspec": {"name": "ir", "language": "R"}}, "cells": []}`: R,
+		`{"metadata": {"kernelspec": {"name": "python3"}}}`:                          Python,
+		`{"metadata": {"language_info": {"name": "scala"}}}`:                         Scala,
+		`{"metadata": {"kernelspec": {"name": "julia-1.10", "language": "julia"}}}`:  Julia,
+	}
+	for data, expected := range kernels {
+		nb, err := InspectNotebook(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nb.Mode != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), data, nb.Mode)
+		}
+	}
+	if _, err := InspectNotebook(strings.NewReader(`["not", "a", "notebook"]`)); err == nil {
+		t.Error("Expected an error for a JSON array")
+	}
+	if _, err := InspectNotebook(strings.NewReader(`{"cells": [`)); err == nil {
+		t.Error("Expected an error for a truncated notebook")
+	}
+}
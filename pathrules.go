@@ -0,0 +1,185 @@
+package mode
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PathRule maps a glob pattern for a path to a Mode.
+// "**" matches zero or more directories, and "*", "?" and "[...]" work like in path.Match.
+// Patterns that start with "/" must match the whole path, and only match absolute paths.
+// Other patterns must match the end of the path.
+type PathRule struct {
+	Pattern string
+	Mode    Mode
+}
+
+// compiledPathRule is a PathRule with the pattern split into segments
+type compiledPathRule struct {
+	PathRule
+	segments    []string
+	specificity int
+	anchored    bool // the pattern starts with "/" and only matches absolute paths
+	broad       bool // the last segment is a wildcard without an extension, like "*"
+}
+
+// PathRules is an ordered collection of path rules, where the most specific matching pattern wins.
+// It is safe to call Add while other goroutines call Match.
+type PathRules struct {
+	mut      sync.RWMutex
+	rules    []compiledPathRule // sorted by specificity, most specific first
+	byName   map[string][]int   // indices of the rules that end with a literal filename, by filename
+	wildcard []int              // indices of the rules that end with a pattern
+}
+
+// builtinPathRules are rules where the directory a file is in decides the mode
+var builtinPathRules = []PathRule{
+	{"**/*.git/config", Config},
+	{"**/.git/info/exclude", Ignore},
+	{"**/.config/git/ignore", Ignore},
+	{"**/.cargo/config", TOML},
+	{"**/.kube/config", YAML},
+	{"**/.ssh/authorized_keys", Config},
+	{"**/.ssh/known_hosts", Config},
+	{"**/debian/control", Config},
+	{"**/debian/rules", Make},
+	{"**/nginx/conf.d/*", Config},
+	{"**/nginx/sites-available/*", Config},
+	{"**/nginx/sites-enabled/*", Config},
+	{"**/polkit-1/rules.d/*.rules", JavaScript},
+	{"*.d/*.conf", Config},
+	{"/etc/cron.d/*", Config},
+	{"/etc/sudoers.d/*", Config},
+	{"/etc/systemd/system/*", Config},
+	{"/etc/systemd/system/*/*.conf", Config},
+	{"/var/log/messages", Log},
+}
+
+// DefaultPathRules are the path rules that Detect looks at before looking at the filename.
+// More rules can be added with DefaultPathRules.Add, also while other goroutines call Detect.
+var DefaultPathRules = NewPathRules()
+
+// NewPathRules returns a collection of path rules, containing the built-in rules
+func NewPathRules() *PathRules {
+	var pr PathRules
+	for _, rule := range builtinPathRules {
+		if err := pr.Add(rule.Pattern, rule.Mode); err != nil {
+			panic(err)
+		}
+	}
+	return &pr
+}
+
+// Add adds a rule for the given pattern and mode.
+// Returns path.ErrBadPattern if the pattern is malformed.
+func (pr *PathRules) Add(pattern string, m Mode) error {
+	anchored := strings.HasPrefix(pattern, "/")
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	if !anchored {
+		// Relative patterns can match at any depth
+		segments = append([]string{"**"}, segments...)
+	}
+	specificity := 0
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil || segment == "" {
+			return path.ErrBadPattern
+		}
+		switch {
+		case segment == "**":
+		case strings.ContainsAny(segment, `*?[\`):
+			specificity += 10 + len(segment) - strings.Count(segment, "*") - strings.Count(segment, "?")
+		default:
+			specificity += 100 + len(segment)
+		}
+	}
+	if anchored {
+		specificity++
+	}
+	last := segments[len(segments)-1]
+	rule := compiledPathRule{
+		PathRule:    PathRule{pattern, m},
+		segments:    segments,
+		specificity: specificity,
+		anchored:    anchored,
+		broad:       strings.ContainsAny(last, `*?[\`) && !strings.Contains(last, "."),
+	}
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+	// Insert the rule after all rules that are at least as specific
+	i := slices.IndexFunc(pr.rules, func(r compiledPathRule) bool { return r.specificity < specificity })
+	if i < 0 {
+		i = len(pr.rules)
+	}
+	pr.rules = slices.Insert(pr.rules, i, rule)
+	pr.index()
+	return nil
+}
+
+// index groups the rules by the last segment of the pattern, so that Match only needs
+// to look at the rules that can match the filename
+func (pr *PathRules) index() {
+	pr.byName = make(map[string][]int)
+	pr.wildcard = pr.wildcard[:0]
+	for i, rule := range pr.rules {
+		last := rule.segments[len(rule.segments)-1]
+		if strings.ContainsAny(last, `*?[\`) {
+			pr.wildcard = append(pr.wildcard, i)
+		} else {
+			pr.byName[last] = append(pr.byName[last], i)
+		}
+	}
+}
+
+// Rules returns the rules, sorted from the most specific to the least specific
+func (pr *PathRules) Rules() []PathRule {
+	pr.mut.RLock()
+	defer pr.mut.RUnlock()
+	rules := make([]PathRule, len(pr.rules))
+	for i, rule := range pr.rules {
+		rules[i] = rule.PathRule
+	}
+	return rules
+}
+
+// Match returns the Mode of the most specific rule that matches the given path, and true.
+// Returns Blank and false if no rule matches.
+func (pr *PathRules) Match(filename string) (Mode, bool) {
+	return pr.match(filename, false)
+}
+
+// match is like Match, but if knownExtension is true, broad rules like /etc/cron.d/* are skipped,
+// so that the extension of a file like /etc/cron.d/backup.sh decides the mode instead
+func (pr *PathRules) match(filename string, knownExtension bool) (Mode, bool) {
+	if pr == nil {
+		return Blank, false
+	}
+	pr.mut.RLock()
+	defer pr.mut.RUnlock()
+	if len(pr.rules) == 0 {
+		return Blank, false
+	}
+	slashPath := filepath.ToSlash(filepath.Clean(filename))
+	absolute := strings.HasPrefix(slashPath, "/")
+	segments := strings.Split(strings.TrimPrefix(slashPath, "/"), "/")
+	named, wildcard := pr.byName[segments[len(segments)-1]], pr.wildcard
+	// Merge the two lists of indices, so that the rules are tried from the most specific one
+	for len(named) > 0 || len(wildcard) > 0 {
+		var i int
+		if len(wildcard) == 0 || (len(named) > 0 && named[0] < wildcard[0]) {
+			i, named = named[0], named[1:]
+		} else {
+			i, wildcard = wildcard[0], wildcard[1:]
+		}
+		rule := &pr.rules[i]
+		if (rule.anchored && !absolute) || (rule.broad && knownExtension) {
+			continue
+		}
+		if matchSegments(rule.segments, segments) {
+			return rule.Mode, true
+		}
+	}
+	return Blank, false
+}
@@ -0,0 +1,105 @@
+package mode
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestPathRules(t *testing.T) {
+	paths := map[string]Mode{
+		"/home/user/project/.git/config":                  Config,
+		"repo.git/config":                                 Config,
+		"/home/user/.kube/config":                         YAML,
+		"/etc/nginx/sites-available/example.com":          Config,
+		"/etc/nginx/sites-enabled/default":                Config,
+		"/etc/systemd/system/getty.target.wants":          Config,
+		"/etc/systemd/system/foo.service.d/override.conf": Config,
+		"/etc/cron.d/backup":                              Config,
+		"debian/rules":                                    Make,
+		"/etc/polkit-1/rules.d/49-nopasswd.rules":         JavaScript,
+		"/usr/share/polkit-1/rules.d/50-default.rules":    JavaScript,
+		"/etc/udev/rules.d/99-usb.rules":                  Config,
+		"/home/user/.cargo/config":                        TOML,
+		"/home/user/project/config":                       Config,
+		"main.go":                                         Go,
+	}
+	for filename, expected := range paths {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
+
+func TestPathRulesSpecificity(t *testing.T) {
+	var pr PathRules
+	for _, rule := range []PathRule{
+		{"**/*.yml", YAML},
+		{".github/workflows/*.yml", Config},
+		{"**/nginx/**", Config},
+		{"/srv/nginx/special.conf", Ini},
+	} {
+		if err := pr.Add(rule.Pattern, rule.Mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := map[string]Mode{
+		"ci.yml": YAML,
+		"/home/user/repo/.github/workflows/ci.yml": Config,
+		".github/workflows/ci.yml":                 Config,
+		".github/dependabot.yml":                   YAML,
+		"/etc/nginx/nginx.conf":                    Config,
+		"/srv/nginx/special.conf":                  Ini,
+	}
+	for filename, expected := range paths {
+		if m, ok := pr.Match(filename); !ok || m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+	if _, ok := pr.Match("main.go"); ok {
+		t.Fail()
+	}
+	if err := pr.Add("[", Go); err == nil {
+		t.Fail()
+	}
+	if rules := pr.Rules(); len(rules) != 4 || rules[0].Pattern != "/srv/nginx/special.conf" {
+		t.Errorf("Unexpected order of rules: %v", rules)
+	}
+}
+
+func TestPathRulesAnchored(t *testing.T) {
+	paths := map[string]Mode{
+		"/etc/systemd/system/x":          Config,
+		"etc/systemd/system/x.sh":        Shell, // relative paths do not match anchored rules
+		"etc/systemd/system/x":           Blank,
+		"/etc/systemd/system/backup.sh":  Shell, // known extensions win over broad rules
+		"/srv/nginx/conf.d/app.lua":      Lua,
+		"/srv/nginx/conf.d/app":          Config,
+		"/etc/systemd/system/web.socket": Config,
+	}
+	for filename, expected := range paths {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
+
+func TestPathRulesConcurrency(t *testing.T) {
+	pr := NewPathRules()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pr.Add(fmt.Sprintf("**/dir%d/*", i), Config)
+		}()
+		go func() {
+			defer wg.Done()
+			pr.Match("/etc/cron.d/backup")
+		}()
+	}
+	wg.Wait()
+	if len(pr.Rules()) != len(builtinPathRules)+4 {
+		t.Errorf("Expected %d rules, got %d", len(builtinPathRules)+4, len(pr.Rules()))
+	}
+}
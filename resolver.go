@@ -0,0 +1,189 @@
+package mode
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ProjectRulesFile is the name of a file that can contain project rules, and the table the rules are in.
+// An empty table name means that the whole file contains rules.
+type ProjectRulesFile struct {
+	Name  string
+	Table string
+}
+
+// ProjectRulesFiles are the files that the Resolver looks for project rules in, like .mode.toml or
+// a [tool.mode] table in pyproject.toml, with [tool.mode.extensions] and so on.
+// When a directory contains more than one of these files, the first one is used.
+var ProjectRulesFiles = []ProjectRulesFile{
+	{".mode.toml", ""},
+	{"pyproject.toml", "tool.mode"},
+}
+
+// projectRules are the rules found in a directory
+type projectRules struct {
+	dir   string
+	rules *Rules
+}
+
+// projectLayers are the project rules that apply to a directory, from the closest directory and up
+type projectLayers struct {
+	layers []projectRules
+	err    error // the first error found when loading the rules
+}
+
+// Resolver finds the Mode and indentation for files by layering project rules over user rules over
+// the built-in detection. Project rules are found by walking up from the directory of the file, and
+// rules in directories closer to the file take precedence. The walk stops at a rules file with
+// "root = true", or at the root of the file system. The rules for each directory are cached, so call
+// Reset if rules files are changed. A Resolver is safe for concurrent use.
+type Resolver struct {
+	User  *Rules // user rules, like the ones from LoadUserRules, may be nil
+	mut   sync.Mutex
+	cache map[string]*projectLayers
+}
+
+// NewResolver returns a Resolver that uses the given user rules, which may be nil
+func NewResolver(user *Rules) *Resolver {
+	return &Resolver{User: user, cache: make(map[string]*projectLayers)}
+}
+
+// Reset clears the cache of project rules
+func (res *Resolver) Reset() {
+	res.mut.Lock()
+	defer res.mut.Unlock()
+	res.cache = make(map[string]*projectLayers)
+}
+
+// project returns the project rules for the given directory, which must be absolute and clean
+func (res *Resolver) project(dir string) *projectLayers {
+	res.mut.Lock()
+	defer res.mut.Unlock()
+	if res.cache == nil {
+		res.cache = make(map[string]*projectLayers)
+	}
+	return res.projectLocked(dir)
+}
+
+// projectLocked returns the project rules for the given directory, while the mutex is held
+func (res *Resolver) projectLocked(dir string) *projectLayers {
+	if pl, ok := res.cache[dir]; ok {
+		return pl
+	}
+	pl := &projectLayers{}
+	rules, err := loadProjectRules(dir)
+	if err != nil {
+		pl.err = err
+	}
+	if rules != nil {
+		pl.layers = append(pl.layers, projectRules{dir, rules})
+	}
+	if parent := filepath.Dir(dir); parent != dir && (rules == nil || !rules.Root) {
+		parentLayers := res.projectLocked(parent)
+		pl.layers = append(pl.layers, parentLayers.layers...)
+		if pl.err == nil {
+			pl.err = parentLayers.err
+		}
+	}
+	res.cache[dir] = pl
+	return pl
+}
+
+// loadProjectRules loads the project rules in the given directory.
+// Returns nil and no error if there are no project rules in the directory.
+func loadProjectRules(dir string) (*Rules, error) {
+	for _, prf := range ProjectRulesFiles {
+		filename := filepath.Join(dir, prf.Name)
+		data, err := os.ReadFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if prf.Table != "" && !bytes.Contains(data, []byte("["+prf.Table)) {
+			// This project file has no rules in it
+			continue
+		}
+		return parseRules(filename, data, prf.Table)
+	}
+	return nil, nil
+}
+
+// layers returns the project rules that apply to the given filename
+func (res *Resolver) layers(filename string) *projectLayers {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return &projectLayers{err: err}
+	}
+	return res.project(filepath.Dir(abs))
+}
+
+// Err returns the first error that was found when loading the project rules for the given filename,
+// like a syntax error in a .mode.toml file. Rules files with errors are skipped by the other methods.
+func (res *Resolver) Err(filename string) error {
+	return res.layers(filename).err
+}
+
+// detectFilename looks for a project or user rule that matches the given filename
+func (res *Resolver) detectFilename(filename string, pl *projectLayers) (Mode, bool) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		abs = filename
+	}
+	for _, layer := range pl.layers {
+		// Globs in project rules are relative to the directory of the rules file,
+		// which is the root that patterns like /gen/*.txt are anchored to
+		rel, err := filepath.Rel(layer.dir, abs)
+		if err != nil {
+			rel = abs
+		}
+		if m, ok := layer.rules.DetectFilename(filepath.Join(string(filepath.Separator), rel)); ok {
+			return m, true
+		}
+	}
+	return res.User.DetectFilename(filename)
+}
+
+// Detect looks at the filename and returns the Mode from the project rules, the user rules or Detect
+func (res *Resolver) Detect(filename string) Mode {
+	if m, ok := res.detectFilename(filename, res.layers(filename)); ok {
+		return m
+	}
+	return Detect(filename)
+}
+
+// DetectFile looks at the filename and contents and returns the Mode from the project rules,
+// the user rules or DetectFile. Rules for filenames are tried before rules for contents.
+func (res *Resolver) DetectFile(filename string, data []byte) Mode {
+	pl := res.layers(filename)
+	if m, ok := res.detectFilename(filename, pl); ok {
+		return m
+	}
+	if text, encoding := DecodeUTF8(data); encoding != EncodingBinary {
+		firstLine, _, _ := bytes.Cut(text, []byte("\n"))
+		for _, layer := range pl.layers {
+			if m, ok := layer.rules.DetectFirstLine(firstLine); ok {
+				return m
+			}
+		}
+		if m, ok := res.User.DetectFirstLine(firstLine); ok {
+			return m
+		}
+	}
+	return DetectFile(filename, data)
+}
+
+// TabsSpaces returns the indentation settings for the given Mode, for a file with the given filename,
+// from the project rules, the user rules or the built-in settings
+func (res *Resolver) TabsSpaces(filename string, m Mode) TabsSpaces {
+	for _, layer := range res.layers(filename).layers {
+		if ts, ok := layer.rules.Indentation[m]; ok {
+			return ts
+		}
+	}
+	return res.User.TabsSpaces(m)
+}
@@ -0,0 +1,79 @@
+package mode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes the given files, with paths relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".mode.toml":         "[extensions]\n\".inc\" = \"PHP\"\n\n[indentation.Python]\nwidth = 2\n",
+		"lib/.mode.toml":     "[globs]\n\"/gen/*.txt\" = \"Log\"\n\n[[firstline]]\nregex = '^%%'\nmode = \"M4\"\n",
+		"lib/gen/build.txt":  "",
+		"app/pyproject.toml": "[project]\nname = \"app\"\ndependencies = [\n  \"x\",\n]\n\n[tool.mode.extensions]\ntxt = \"Markdown\"\n",
+		"root/.mode.toml":    "root = true\n\n[filenames]\n\"notes\" = \"Text\"\n",
+	})
+	user, err := ParseRules("user.toml", []byte("[extensions]\n\".h\" = \"C\"\n\".inc\" = \"POV-Ray\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := NewResolver(user)
+	filenames := map[string]Mode{
+		"header.h":          C,        // user rules
+		"page.inc":          PHP,      // project rules over user rules
+		"lib/page.inc":      PHP,      // project rules from a parent directory
+		"lib/gen/build.txt": Log,      // globs are relative to the rules file
+		"lib/build.txt":     Text,     // built-in rules
+		"app/README.txt":    Markdown, // pyproject.toml
+		"root/page.inc":     POV,      // root = true stops the walk
+		"root/notes":        Text,
+		"main.go":           Go,
+	}
+	for name, expected := range filenames {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if m := res.Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), name, m)
+		}
+		if err := res.Err(filename); err != nil {
+			t.Errorf("Expected no error for %s, got %v", name, err)
+		}
+	}
+	if m := res.DetectFile(filepath.Join(dir, "lib", "defs"), []byte("%% definitions\n")); m != M4 {
+		t.Errorf("Expected M4, got %s", m)
+	}
+	if ts := res.TabsSpaces(filepath.Join(dir, "lib", "main.py"), Python); ts != (TabsSpaces{2, true}) {
+		t.Errorf("Expected 2 spaces for Python, got %v", ts)
+	}
+	if ts := res.TabsSpaces(filepath.Join(dir, "root", "main.py"), Python); ts != Mode(Python).TabsSpaces() {
+		t.Errorf("Expected the built-in indentation for Python, got %v", ts)
+	}
+
+	// Changes to the rules files are seen after Reset
+	writeFiles(t, dir, map[string]string{".mode.toml": "[extensions]\n\".inc\" = \"NoSuchMode\"\n"})
+	if m := res.Detect(filepath.Join(dir, "page.inc")); m != PHP {
+		t.Errorf("Expected the cached rules to give PHP, got %s", m)
+	}
+	res.Reset()
+	if m := res.Detect(filepath.Join(dir, "page.inc")); m != POV {
+		t.Errorf("Expected the user rules to give POV-Ray, got %s", m)
+	}
+	if err := res.Err(filepath.Join(dir, "page.inc")); err == nil {
+		t.Error("Expected an error for the invalid .mode.toml")
+	}
+}
@@ -0,0 +1,455 @@
+package mode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Rules are user-defined rules for detecting modes and for indentation, typically loaded from a TOML file.
+// They take precedence over the built-in rules, in this order: globs, filenames, extensions, shebangs
+// and first line regular expressions. If none of them match, the built-in detection is used.
+//
+// An example rules file:
+//
+//	[globs]
+//	"nginx/**/*.conf" = "Config"
+//
+//	[filenames]
+//	"BUCK" = "Starlark"
+//
+//	[extensions]
+//	".h" = "C"
+//
+//	[shebangs]
+//	deno = "TypeScript"
+//
+//	[[firstline]]
+//	regex = '^<\?php'
+//	mode = "PHP"
+//
+//	[indentation.Python]
+//	spaces = true
+//	width = 4
+//
+// In project rules, see Resolver, "root = true" at the top of the file stops the search for rules in parent directories.
+type Rules struct {
+	Globs       *PathRules          // path patterns, see PathRule
+	Filenames   map[string]Mode     // exact filenames, like "BUCK"
+	Extensions  map[string]Mode     // extensions, like ".h", that are matched case-insensitively
+	Shebangs    map[string]Mode     // interpreter names, like "deno" for "#!/usr/bin/env deno"
+	FirstLines  []FirstLineRule     // regular expressions for the first line of a file
+	Indentation map[Mode]TabsSpaces // indentation settings per mode
+	Root        bool                // for project rules, stop looking for rules in parent directories
+}
+
+// FirstLineRule is a regular expression for the first line of a file, and the Mode it gives
+type FirstLineRule struct {
+	Regexp *regexp.Regexp
+	Mode   Mode
+}
+
+// RuleError is an error in a rules file, with the line it was found on
+type RuleError struct {
+	Filename string
+	Line     int
+	Message  string
+}
+
+// Error returns the error message, prefixed with the filename and line number
+func (e *RuleError) Error() string {
+	if e.Filename == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Filename, e.Line, e.Message)
+}
+
+// UserRulesPath returns the path to the rules file of the current user,
+// like ~/.config/mode/rules.toml, or $XDG_CONFIG_HOME/mode/rules.toml if XDG_CONFIG_HOME is set
+func UserRulesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mode", "rules.toml"), nil
+}
+
+// LoadUserRules loads the rules file of the current user, as given by UserRulesPath.
+// Returns empty rules if there is no such file.
+func LoadUserRules() (*Rules, error) {
+	filename, err := UserRulesPath()
+	if err != nil {
+		return &Rules{}, nil
+	}
+	r, err := LoadRules(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Rules{}, nil
+	}
+	return r, err
+}
+
+// LoadRules reads and parses the given rules file
+func LoadRules(filename string) (*Rules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(filename, data)
+}
+
+// ParseRules parses the given TOML data as rules. The filename is only used in error messages.
+// Returns a *RuleError if the data is not valid, like if a mode name is unknown.
+func ParseRules(filename string, data []byte) (*Rules, error) {
+	return parseRules(filename, data, "")
+}
+
+// parseRules parses the rules in the given TOML data. If prefix is not empty, only the tables
+// within the prefix table are used, like [tool.mode.extensions] for the prefix "tool.mode".
+func parseRules(filename string, data []byte, prefix string) (*Rules, error) {
+	if prefix != "" {
+		data = tomlSection(data, prefix)
+	}
+	doc, err := parseTOML(data)
+	if err != nil {
+		var ruleErr *RuleError
+		if errors.As(err, &ruleErr) {
+			ruleErr.Filename = filename
+		}
+		return nil, err
+	}
+	var r Rules
+	for _, table := range doc.tables {
+		if prefix != "" {
+			if table.name != prefix && !strings.HasPrefix(table.name, prefix+".") {
+				continue
+			}
+			// Use a copy of the table, with the name relative to the prefix
+			relative := *table
+			relative.name = strings.TrimPrefix(strings.TrimPrefix(table.name, prefix), ".")
+			table = &relative
+		}
+		if err := r.addTable(table); err != nil {
+			err.Filename = filename
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+// tomlSection blanks out the lines of the given TOML data that are not within the given table or
+// its subtables, while keeping the line numbers. This makes it possible to read rules from files
+// like pyproject.toml, where the other tables may use TOML features that parseTOML does not support.
+func tomlSection(data []byte, table string) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	var (
+		inside    bool
+		depth     int    // the depth of the brackets in multi-line arrays
+		multiline string // the delimiter of the current multi-line string, if any
+	)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(string(line))
+		switch {
+		case multiline != "":
+			if strings.Count(trimmed, multiline)%2 == 1 {
+				multiline = ""
+			}
+		case depth == 0 && strings.HasPrefix(trimmed, "["):
+			if name, _, err := parseTOMLHeader(trimmed); err == nil {
+				inside = name == table || strings.HasPrefix(name, table+".")
+			}
+		default:
+			for _, delimiter := range []string{`"""`, "'''"} {
+				if strings.Count(trimmed, delimiter)%2 == 1 {
+					multiline = delimiter
+				}
+			}
+			if multiline == "" {
+				depth = max(0, depth+tomlBracketDepth(trimmed))
+			}
+		}
+		if !inside {
+			lines[i] = nil
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// tomlBracketDepth returns the number of opened minus the number of closed square brackets
+// in the given line, outside of quotes and comments
+func tomlBracketDepth(line string) int {
+	if i := indexOutsideQuotes(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	depth := 0
+	for {
+		i := indexOutsideQuotes(line, '[')
+		j := indexOutsideQuotes(line, ']')
+		switch {
+		case i >= 0 && (j < 0 || i < j):
+			depth++
+			line = line[i+1:]
+		case j >= 0:
+			depth--
+			line = line[j+1:]
+		default:
+			return depth
+		}
+	}
+}
+
+// addTable adds the rules from the given TOML table
+func (r *Rules) addTable(table *tomlTable) *RuleError {
+	switch {
+	case table.name == "" && len(table.keys) == 0:
+		return nil
+	case table.name == "globs":
+		return table.eachMode(func(pattern string, m Mode, line int) *RuleError {
+			if r.Globs == nil {
+				r.Globs = &PathRules{}
+			}
+			if err := r.Globs.Add(pattern, m); err != nil {
+				return &RuleError{Line: line, Message: "invalid glob pattern " + pattern}
+			}
+			return nil
+		})
+	case table.name == "filenames":
+		return table.eachMode(func(name string, m Mode, _ int) *RuleError {
+			if r.Filenames == nil {
+				r.Filenames = make(map[string]Mode)
+			}
+			r.Filenames[name] = m
+			return nil
+		})
+	case table.name == "extensions":
+		return table.eachMode(func(ext string, m Mode, _ int) *RuleError {
+			if r.Extensions == nil {
+				r.Extensions = make(map[string]Mode)
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			r.Extensions[strings.ToLower(ext)] = m
+			return nil
+		})
+	case table.name == "shebangs":
+		return table.eachMode(func(interpreter string, m Mode, _ int) *RuleError {
+			if r.Shebangs == nil {
+				r.Shebangs = make(map[string]Mode)
+			}
+			r.Shebangs[interpreter] = m
+			return nil
+		})
+	case table.name == "firstline":
+		expr, err := table.stringValue("regex")
+		if err != nil {
+			return err
+		}
+		re, compileErr := regexp.Compile(expr)
+		if compileErr != nil {
+			return &RuleError{Line: table.values["regex"].line, Message: "invalid regex: " + compileErr.Error()}
+		}
+		m, err := table.modeValue("mode")
+		if err != nil {
+			return err
+		}
+		if err := table.onlyKeys("regex", "mode"); err != nil {
+			return err
+		}
+		r.FirstLines = append(r.FirstLines, FirstLineRule{re, m})
+	case strings.HasPrefix(table.name, "indentation."):
+		name := strings.TrimPrefix(table.name, "indentation.")
+		m, ok := ParseMode(name)
+		if !ok {
+			return &RuleError{Line: table.line, Message: "unknown mode " + name}
+		}
+		ts := m.TabsSpaces()
+		if v, ok := table.values["spaces"]; ok {
+			spaces, ok := v.value.(bool)
+			if !ok {
+				return &RuleError{Line: v.line, Message: "spaces must be true or false"}
+			}
+			ts.Spaces = spaces
+		}
+		if v, ok := table.values["width"]; ok {
+			width, ok := v.value.(int64)
+			if !ok || width < 1 || width > 16 {
+				return &RuleError{Line: v.line, Message: "width must be a number from 1 to 16"}
+			}
+			ts.PerTab = int(width)
+		}
+		if err := table.onlyKeys("spaces", "width"); err != nil {
+			return err
+		}
+		if r.Indentation == nil {
+			r.Indentation = make(map[Mode]TabsSpaces)
+		}
+		r.Indentation[m] = ts
+	case table.name == "":
+		for _, key := range table.keys {
+			v := table.values[key]
+			if key != "root" {
+				return &RuleError{Line: v.line, Message: "key " + key + " must be in a table, like [extensions]"}
+			}
+			root, ok := v.value.(bool)
+			if !ok {
+				return &RuleError{Line: v.line, Message: "root must be true or false"}
+			}
+			r.Root = root
+		}
+	default:
+		return &RuleError{Line: table.line, Message: "unknown table [" + table.name + "]"}
+	}
+	return nil
+}
+
+// eachMode calls f for each key in the table, where the value must be a mode name
+func (table *tomlTable) eachMode(f func(key string, m Mode, line int) *RuleError) *RuleError {
+	for _, key := range table.keys {
+		m, err := table.modeValue(key)
+		if err != nil {
+			return err
+		}
+		if err := f(key, m, table.values[key].line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringValue returns the string value for the given key
+func (table *tomlTable) stringValue(key string) (string, *RuleError) {
+	v, ok := table.values[key]
+	if !ok {
+		return "", &RuleError{Line: table.line, Message: "missing " + key + " in [" + table.name + "]"}
+	}
+	s, ok := v.value.(string)
+	if !ok {
+		return "", &RuleError{Line: v.line, Message: key + " must be a string"}
+	}
+	return s, nil
+}
+
+// modeValue returns the mode for the mode name that is the value of the given key
+func (table *tomlTable) modeValue(key string) (Mode, *RuleError) {
+	name, err := table.stringValue(key)
+	if err != nil {
+		return Blank, err
+	}
+	m, ok := ParseMode(name)
+	if !ok {
+		return Blank, &RuleError{Line: table.values[key].line, Message: "unknown mode " + name}
+	}
+	return m, nil
+}
+
+// onlyKeys returns an error for the first key in the table that is not one of the given keys
+func (table *tomlTable) onlyKeys(keys ...string) *RuleError {
+	for _, key := range table.keys {
+		if !hasS(keys, key) {
+			return &RuleError{Line: table.values[key].line, Message: "unknown key " + key + " in [" + table.name + "]"}
+		}
+	}
+	return nil
+}
+
+// shebangInterpreter returns the name of the interpreter in the given shebang line,
+// like "python3" for "#!/usr/bin/env python3", or an empty string
+func shebangInterpreter(firstLine []byte) string {
+	if !bytes.HasPrefix(firstLine, []byte("#!")) {
+		return ""
+	}
+	fields := strings.Fields(string(firstLine[2:]))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip options like -S and variable assignments
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+	return interpreter
+}
+
+// DetectFilename looks for a glob, filename or extension rule that matches the given filename.
+// Returns false if none of the rules match.
+func (r *Rules) DetectFilename(filename string) (Mode, bool) {
+	if r == nil {
+		return Blank, false
+	}
+	if m, ok := r.Globs.Match(filename); ok {
+		return m, true
+	}
+	baseFilename := filepath.Base(filename)
+	if m, ok := r.Filenames[baseFilename]; ok {
+		return m, true
+	}
+	if ext := filepath.Ext(baseFilename); ext != "" {
+		if m, ok := r.Extensions[strings.ToLower(ext)]; ok {
+			return m, true
+		}
+	}
+	return Blank, false
+}
+
+// DetectFirstLine looks for a shebang or first line rule that matches the given first line of a file.
+// Returns false if none of the rules match.
+func (r *Rules) DetectFirstLine(firstLine []byte) (Mode, bool) {
+	if r == nil {
+		return Blank, false
+	}
+	firstLine = bytes.TrimSuffix(bytes.TrimPrefix(firstLine, utf8BOM), []byte("\r"))
+	if interpreter := shebangInterpreter(firstLine); interpreter != "" {
+		if m, ok := r.Shebangs[interpreter]; ok {
+			return m, true
+		}
+	}
+	for _, rule := range r.FirstLines {
+		if rule.Regexp.Match(firstLine) {
+			return rule.Mode, true
+		}
+	}
+	return Blank, false
+}
+
+// Detect looks at the filename, like the Detect function, but the rules are tried first
+func (r *Rules) Detect(filename string) Mode {
+	if m, ok := r.DetectFilename(filename); ok {
+		return m
+	}
+	return Detect(filename)
+}
+
+// DetectFile looks at the filename and the contents, like the DetectFile function, but the rules are tried first
+func (r *Rules) DetectFile(filename string, data []byte) Mode {
+	if m, ok := r.DetectFilename(filename); ok {
+		return m
+	}
+	if text, encoding := DecodeUTF8(data); encoding != EncodingBinary {
+		firstLine, _, _ := bytes.Cut(text, []byte("\n"))
+		if m, ok := r.DetectFirstLine(firstLine); ok {
+			return m
+		}
+	}
+	return DetectFile(filename, data)
+}
+
+// TabsSpaces returns the indentation settings for the given mode, from the rules if there is one
+func (r *Rules) TabsSpaces(m Mode) TabsSpaces {
+	if r != nil {
+		if ts, ok := r.Indentation[m]; ok {
+			return ts
+		}
+	}
+	return m.TabsSpaces()
+}
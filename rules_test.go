@@ -0,0 +1,165 @@
+package mode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testRules = `# Rules for testing
+[globs]
+"nginx/**/*.conf" = "Config"
+
+[filenames] # [not a header]
+"BUCK" = "Starlark"
+"Build\u0046ile" = "Starlark"
+
+[extensions]
+".h" = "C"
+inc = 'POV-Ray' # without a leading dot
+
+[shebangs]
+deno = "TypeScript"
+
+[[firstline]]
+regex = '^<\?php'
+mode = "PHP"
+
+[indentation.Python]
+width = 2
+
+[indentation."Go"]
+spaces = true
+`
+
+func TestParseRules(t *testing.T) {
+	r, err := ParseRules("rules.toml", []byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filenames := map[string]Mode{
+		"etc/nginx/sites/default.conf": Config,
+		"BUCK":                         Starlark,
+		"BuildFile":                    Starlark,
+		"include/util.h":               C,
+		"UTIL.H":                       C,
+		"scene.inc":                    POV,
+		"main.go":                      Go,
+	}
+	for filename, expected := range filenames {
+		if m := r.Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+	contents := map[string]Mode{
+		"#!/usr/bin/env -S deno run\nconsole.log(1)\n": TypeScript,
+		"<?php echo 1; ?>\n":                           PHP,
+		"#!/bin/bash\necho hi\n":                       Shell,
+	}
+	for data, expected := range contents {
+		if m := r.DetectFile("script", []byte(data)); m != expected {
+			t.Errorf("Expected %s for %q, got %s", Mode(expected), data, m)
+		}
+	}
+	if ts := r.TabsSpaces(Python); ts != (TabsSpaces{2, true}) {
+		t.Errorf("Expected 2 spaces for Python, got %v", ts)
+	}
+	if ts := r.TabsSpaces(Go); ts != (TabsSpaces{4, true}) {
+		t.Errorf("Expected 4 spaces for Go, got %v", ts)
+	}
+	if ts := r.TabsSpaces(C); ts != Mode(C).TabsSpaces() {
+		t.Errorf("Expected the built-in indentation for C, got %v", ts)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	invalid := map[string]int{
+		"[extensions]\n\".h\" = \"NoSuchMode\"\n":      2,
+		"[extensions]\n.h = \"C\"\n":                   2,
+		"\n\n[nosuchtable]\n":                          3,
+		"[globs]\n\"[\" = \"C\"\n":                     2,
+		"[[firstline]]\nregex = '('\nmode = \"C\"\n":   2,
+		"[[firstline]]\nregex = 'x'\n":                 1,
+		"[indentation.Go]\nwidth = \"4\"\n":            2,
+		"[extensions]\n\".h\" = \"C\"\n\".h\" = \"C\"": 3,
+		"[filenames\n":                                 1,
+		"mode = \"C\"\n":                               1,
+		"[shebangs]\nsh = \"Shell\nx = 1\n":            2,
+		"[shebangs]\n\"\\x73h\" = \"Shell\"\n":         2,
+		"[shebangs]\n\"s\\a\" = \"Shell\"\n":           2,
+		"[shebangs]\n\"\\163h\" = \"Shell\"\n":         2,
+		"[filenames] x\n":                              1,
+	}
+	for data, line := range invalid {
+		_, err := ParseRules("rules.toml", []byte(data))
+		var ruleErr *RuleError
+		if !errors.As(err, &ruleErr) {
+			t.Errorf("Expected a RuleError for %q, got %v", data, err)
+			continue
+		}
+		if ruleErr.Line != line || ruleErr.Filename != "rules.toml" {
+			t.Errorf("Expected an error at rules.toml:%d for %q, got %v", line, data, err)
+		}
+	}
+}
+
+func TestLoadUserRules(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	r, err := LoadUserRules()
+	if err != nil || r == nil {
+		t.Fatalf("Expected empty rules when there is no rules file, got %v", err)
+	}
+	filename, err := UserRulesPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte("[extensions]\nh = \"C\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err = LoadUserRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := r.Detect("main.h"); m != C {
+		t.Errorf("Expected C, got %s", m)
+	}
+}
+
+func TestParseRulesSection(t *testing.T) {
+	data := `[tool.mode.extensions]
+txt = "Markdown"
+[tool.other]
+matrix = [
+  ["a", "b"],
+["c"],
+]
+notes = """
+[tool.mode.filenames]
+"""
+[tool.mode.filenames] # [comment]
+"BUCK" = "Starlark"
+[tool.other.deps]
+x = 1
+`
+	r, err := parseRules("pyproject.toml", []byte(data), "tool.mode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := r.Detect("notes.txt"); m != Markdown {
+		t.Errorf("Expected Markdown for notes.txt, got %s", m)
+	}
+	if m := r.Detect("BUCK"); m != Starlark {
+		t.Errorf("Expected Starlark for BUCK, got %s", m)
+	}
+	data = "[tool.mode.extensions]\ntxt = \"Markdown\"\nignored = [\n  [\"a\", \"b\"],\n]\n"
+	if _, err := parseRules("pyproject.toml", []byte(data), "tool.mode"); err == nil {
+		t.Error("Expected the array within [tool.mode.extensions] to be kept and give an error")
+	}
+}
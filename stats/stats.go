@@ -0,0 +1,160 @@
+// Package stats counts files and lines of code, comments and blank lines per mode
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xyproto/mode"
+)
+
+// Counts contains the number of files and lines for a mode, or for a single file
+type Counts struct {
+	Mode     mode.Mode `json:"-"`
+	Language string    `json:"language"`
+	Files    int       `json:"files"`
+	Code     int       `json:"code"`
+	Comment  int       `json:"comment"`
+	Blank    int       `json:"blank"`
+}
+
+// File contains the line counts for a single file
+type File struct {
+	Path string `json:"path"`
+	Counts
+}
+
+// Result contains the line counts for all files in a directory tree
+type Result struct {
+	Languages []Counts `json:"languages"` // sorted by lines of code, most first
+	Total     Counts   `json:"total"`
+	Files     []File   `json:"files"` // in the order they were found
+}
+
+// ignoreFilenames are the files that ignore rules are read from, in each directory
+var ignoreFilenames = []string{".gitignore", ".ignore"}
+
+// CountBytes detects the mode of the given file and counts the lines in it.
+// Returns false if no mode could be detected, or if the file looks binary.
+func CountBytes(filename string, data []byte) (Counts, bool) {
+	text, enc := mode.DecodeUTF8(data)
+	if enc == mode.EncodingBinary {
+		return Counts{}, false
+	}
+	m := mode.DetectFile(filename, data)
+	if m == mode.Blank {
+		return Counts{}, false
+	}
+	c := Counts{Mode: m, Language: m.String(), Files: 1}
+	for _, kind := range m.ClassifyLines(text) {
+		switch kind {
+		case mode.BlankLine:
+			c.Blank++
+		case mode.CommentLine, mode.DocstringLine:
+			c.Comment++
+		default: // code and lines inside of strings
+			c.Code++
+		}
+	}
+	return c, true
+}
+
+// Count walks the given directory in the given file system, detects the mode of each file
+// and counts the lines of code, comments and blank lines.
+// Files and directories that are ignored by .gitignore or .ignore files are skipped,
+// and so are .git directories, binary files and files where no mode could be detected.
+func Count(fsys fs.FS, root string) (*Result, error) {
+	var (
+		ignore   mode.IgnoreRules
+		perMode  = make(map[mode.Mode]*Counts)
+		result   Result
+		relative = func(p string) string {
+			if root == "." {
+				return p
+			}
+			return strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		}
+	)
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := relative(p)
+		if d.IsDir() {
+			if rel != "" && (d.Name() == ".git" || ignore.Ignored(rel, true)) {
+				return fs.SkipDir
+			}
+			for _, ignoreFilename := range ignoreFilenames {
+				if data, err := fs.ReadFile(fsys, path.Join(p, ignoreFilename)); err == nil {
+					ignore.Add(data, rel)
+				}
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || ignore.Ignored(rel, false) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		// The whole path is used, so that path rules like debian/rules apply
+		c, ok := CountBytes(p, data)
+		if !ok {
+			return nil
+		}
+		result.Files = append(result.Files, File{Path: rel, Counts: c})
+		if total, ok := perMode[c.Mode]; ok {
+			total.add(c)
+		} else {
+			perMode[c.Mode] = &c
+		}
+		result.Total.add(c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Total.Language = "Total"
+	for _, c := range perMode {
+		result.Languages = append(result.Languages, *c)
+	}
+	slices.SortFunc(result.Languages, func(a, b Counts) int {
+		if a.Code != b.Code {
+			return b.Code - a.Code
+		}
+		return strings.Compare(a.Language, b.Language)
+	})
+	return &result, nil
+}
+
+// add adds the given counts to these counts
+func (c *Counts) add(other Counts) {
+	c.Files += other.Files
+	c.Code += other.Code
+	c.Comment += other.Comment
+	c.Blank += other.Blank
+}
+
+// WriteJSON writes the result as indented JSON to the given writer
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteTable writes the per-language counts and the total as a plain text table to the given writer
+func (r *Result) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Language\tFiles\tBlank\tComment\tCode\t")
+	for _, c := range append(slices.Clone(r.Languages), r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t\n", c.Language, c.Files, c.Blank, c.Comment, c.Code)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/xyproto/mode"
+)
+
+var testFS = fstest.MapFS{
+	"project/.gitignore":       {Data: []byte("build/\n*.tmp\n")},
+	"project/main.go":          {Data: []byte("package main\n\n// main does nothing\nfunc main() {}\n")},
+	"project/util/util.go":     {Data: []byte("package util\n")},
+	"project/run.sh":           {Data: []byte("#!/bin/sh\n# run it\necho hi\n")},
+	"project/build/out.go":     {Data: []byte("package out\n")},
+	"project/scratch.tmp":      {Data: []byte("package tmp\n")},
+	"project/image.png":        {Data: []byte("\x89PNG\r\n\x1a\n\x00\x00")},
+	"project/.git/config":      {Data: []byte("[core]\n")},
+	"project/sub/.gitignore":   {Data: []byte("!keep.tmp\n")},
+	"project/sub/keep.tmp.go":  {Data: []byte("package sub\n")},
+	"project/sub/ignored.tmp":  {Data: []byte("x\n")},
+	"project/sub/nothing.xyzw": {Data: []byte("")},
+}
+
+func TestCount(t *testing.T) {
+	r, err := Count(testFS, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Total.Files != 6 {
+		t.Fatalf("Expected 6 files, got %d: %v", r.Total.Files, r.Files)
+	}
+	if len(r.Languages) != 3 || r.Languages[0].Mode != mode.Go || r.Languages[1].Mode != mode.Ignore || r.Languages[2].Mode != mode.Shell {
+		t.Fatalf("Expected Go, Ignore and Shell, got %v", r.Languages)
+	}
+	goCounts := r.Languages[0]
+	if goCounts.Files != 3 || goCounts.Code != 4 || goCounts.Comment != 1 || goCounts.Blank != 1 {
+		t.Fatalf("Unexpected counts for Go: %+v", goCounts)
+	}
+	var buf bytes.Buffer
+	if err := r.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Total") {
+		t.Fail()
+	}
+	buf.Reset()
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"language": "Go"`) {
+		t.Fail()
+	}
+}
+
+func TestCountPathRules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home/debian/rules": {Data: []byte("%:\n\tdh $@\n")},
+		"home/.kube/config": {Data: []byte("apiVersion: v1\nkind: Config\n")},
+	}
+	r, err := Count(fsys, "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := make(map[string]mode.Mode)
+	for _, f := range r.Files {
+		modes[f.Path] = f.Mode
+	}
+	if m := modes["debian/rules"]; m != mode.Make {
+		t.Errorf("Expected Make for debian/rules, got %s", m)
+	}
+	if m := modes[".kube/config"]; m != mode.YAML {
+		t.Errorf("Expected YAML for .kube/config, got %s", m)
+	}
+}
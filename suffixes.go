@@ -0,0 +1,107 @@
+package mode
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Template is a template language that wraps the contents of a file, like Jinja for index.html.j2
+type Template int
+
+const (
+	TemplateNone    Template = iota // not a template
+	TemplateGeneric                 // .in, .template and .tpl files, where the template language is unknown
+	TemplateJinja                   // Jinja templates
+	TemplateERB                     // Embedded Ruby templates
+	TemplateGo                      // Go templates
+)
+
+// BackupSuffixes are filename suffixes for backup, temporary and leftover files.
+// They are stripped before the mode of the inner filename is detected, ie. for nginx.conf.bak.
+var BackupSuffixes = []string{"~", ".bak", ".backup", ".dpkg-dist", ".dpkg-new", ".dpkg-old", ".old", ".orig", ".pacnew", ".pacsave", ".rpmnew", ".rpmsave", ".save", ".tmp"}
+
+// TemplateSuffixes maps filename extensions of template files to template languages.
+// They are stripped before the mode of the inner filename is detected, ie. for settings.json.in.
+var TemplateSuffixes = map[string]Template{
+	".in":       TemplateGeneric,
+	".template": TemplateGeneric,
+	".tpl":      TemplateGeneric,
+	".j2":       TemplateJinja,
+	".jinja":    TemplateJinja,
+	".jinja2":   TemplateJinja,
+	".erb":      TemplateERB,
+	".gotmpl":   TemplateGo,
+	".tmpl":     TemplateGo,
+}
+
+// String returns a short string representing the given template language
+func (t Template) String() string {
+	switch t {
+	case TemplateNone:
+		return "none"
+	case TemplateGeneric:
+		return "template"
+	case TemplateJinja:
+		return "Jinja"
+	case TemplateERB:
+		return "ERB"
+	case TemplateGo:
+		return "Go template"
+	default:
+		return "?"
+	}
+}
+
+// Mode returns the Mode for the given template language, if it has its own mode, like ERB for TemplateERB.
+// Returns Blank for template languages where the mode of the inner file is used.
+func (t Template) Mode() Mode {
+	if t == TemplateERB {
+		return ERB
+	}
+	return Blank
+}
+
+// stripSuffixes repeatedly removes backup suffixes, Emacs auto-save markers (#notes.md#) and
+// template suffixes from the given base filename.
+// Returns the inner filename and the outermost template language that was found, if any.
+func stripSuffixes(baseFilename string) (string, Template) {
+	var (
+		name     = baseFilename
+		template = TemplateNone
+	)
+	for changed := true; changed; {
+		changed = false
+		if len(name) > 2 && strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#") {
+			name = name[1 : len(name)-1]
+			changed = true
+		}
+		for _, suffix := range BackupSuffixes {
+			if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+				name = strings.TrimSuffix(name, suffix)
+				changed = true
+			}
+		}
+		ext := filepath.Ext(name)
+		if t, ok := TemplateSuffixes[strings.ToLower(ext)]; ok && len(name) > len(ext) {
+			name = strings.TrimSuffix(name, ext)
+			if template == TemplateNone {
+				template = t
+			}
+			changed = true
+		}
+	}
+	return name, template
+}
+
+// DetectTemplate looks at the filename and tries to guess what could be an appropriate editor mode,
+// like Detect, but also returns the template language that wraps the file, if there is one.
+// For example, index.html.j2 gives HTML and TemplateJinja, and index.html.erb gives HTML and TemplateERB.
+// Detect gives the same mode, except for template languages that have their own mode,
+// where Detect gives that mode instead, like ERB for both index.html.erb and view.erb.
+func DetectTemplate(filename string) (Mode, Template) {
+	inner, template := stripSuffixes(filepath.Base(filename))
+	if template == TemplateNone {
+		return Detect(filename), TemplateNone
+	}
+	return Detect(filepath.Join(filepath.Dir(filename), inner)), template
+}
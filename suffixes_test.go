@@ -0,0 +1,57 @@
+package mode
+
+import (
+	"testing"
+)
+
+func TestStripSuffixes(t *testing.T) {
+	suffixed := map[string]Mode{
+		"nginx.conf.bak":          Config,
+		"main.go.orig":            Go,
+		"config.yml~":             YAML,
+		"#notes.md#":              Markdown,
+		"foo.py.rej":              Diff,
+		"settings.json.in":        JSON,
+		"Dockerfile.template":     Docker,
+		"index.html.j2":           HTML,
+		"/etc/pacman.conf.pacnew": Config,
+		"main.c.orig~":            C,
+	}
+	for filename, expected := range suffixed {
+		if m := Detect(filename); m != expected {
+			t.Errorf("Expected %s for %s, got %s", Mode(expected), filename, m)
+		}
+	}
+}
+
+func TestDetectTemplate(t *testing.T) {
+	templates := []struct {
+		filename string
+		m        Mode
+		template Template
+	}{
+		{"index.html.j2", HTML, TemplateJinja},
+		{"roles/web/templates/nginx.conf.jinja2", Config, TemplateJinja},
+		{"config.yaml.tmpl", YAML, TemplateGo},
+		{"Makefile.in", Make, TemplateGeneric},
+		{"settings.json.in.bak", JSON, TemplateGeneric},
+		{"main.go", Go, TemplateNone},
+		{"nginx.conf.bak", Config, TemplateNone},
+		{"index.html.erb", HTML, TemplateERB},
+		{"Show.HTML.ERB", HTML, TemplateERB},
+	}
+	for _, test := range templates {
+		if m, template := DetectTemplate(test.filename); m != test.m || template != test.template {
+			t.Errorf("Expected %s and %s for %s, got %s and %s", Mode(test.m), test.template, test.filename, m, template)
+		}
+	}
+	// Template languages with their own mode are detected as that mode
+	for _, filename := range []string{"index.html.erb", "view.erb", "config.yml.erb.bak"} {
+		if m := Detect(filename); m != ERB {
+			t.Errorf("Expected ERB for %s, got %s", filename, m)
+		}
+		if _, template := DetectTemplate(filename); template.Mode() != ERB {
+			t.Errorf("Expected the template of %s to have the ERB mode", filename)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package mode
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlValue is a string, int64 or bool value from a TOML file, and the line it was found on
+type tomlValue struct {
+	value any
+	line  int
+}
+
+// tomlTable is a [table] or an [[array]] entry in a TOML file, with the keys in the order they were found
+type tomlTable struct {
+	name   string
+	line   int
+	keys   []string
+	values map[string]tomlValue
+}
+
+// tomlDocument is a parsed TOML file.
+// The root table has an empty name, and tables from [[array]] headers may have the same name.
+type tomlDocument struct {
+	tables []*tomlTable
+}
+
+// parseTOML parses the subset of TOML that is needed for rule files:
+// comments, [table] and [[array]] headers with dotted names, and key = value lines where
+// the value is a basic or literal string, an integer or a boolean.
+// Returns a *RuleError with the line number if the data can not be parsed.
+func parseTOML(data []byte) (*tomlDocument, error) {
+	var (
+		doc     tomlDocument
+		current = &tomlTable{values: make(map[string]tomlValue)}
+		seen    = make(map[string]bool)
+	)
+	doc.tables = append(doc.tables, current)
+	for i, byteLine := range bytes.Split(data, []byte("\n")) {
+		lineNumber := i + 1
+		line := strings.TrimSpace(string(byteLine))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, array, err := parseTOMLHeader(line)
+			if err != nil {
+				return nil, &RuleError{Line: lineNumber, Message: err.Error()}
+			}
+			if !array {
+				if seen[name] {
+					return nil, &RuleError{Line: lineNumber, Message: "table [" + name + "] is defined more than once"}
+				}
+				seen[name] = true
+			}
+			current = &tomlTable{name: name, line: lineNumber, values: make(map[string]tomlValue)}
+			doc.tables = append(doc.tables, current)
+			continue
+		}
+		eq := indexOutsideQuotes(line, '=')
+		if eq < 0 {
+			return nil, &RuleError{Line: lineNumber, Message: "expected key = value"}
+		}
+		parts, err := parseTOMLKey(line[:eq])
+		if err != nil {
+			return nil, &RuleError{Line: lineNumber, Message: err.Error()}
+		}
+		if len(parts) != 1 {
+			return nil, &RuleError{Line: lineNumber, Message: "dotted keys are not supported: " + strings.TrimSpace(line[:eq])}
+		}
+		key := parts[0]
+		if _, ok := current.values[key]; ok {
+			return nil, &RuleError{Line: lineNumber, Message: "key " + strconv.Quote(key) + " is defined more than once"}
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, &RuleError{Line: lineNumber, Message: err.Error()}
+		}
+		current.keys = append(current.keys, key)
+		current.values[key] = tomlValue{value, lineNumber}
+	}
+	return &doc, nil
+}
+
+// parseTOMLHeader parses a [table] or [[array]] header, which may be followed by a comment.
+// Returns the dotted name of the table, and true if it is an [[array]] header.
+func parseTOMLHeader(line string) (string, bool, error) {
+	if i := indexOutsideQuotes(line, '#'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	array := strings.HasPrefix(line, "[[")
+	end := strings.LastIndex(line, "]")
+	if end < 0 || (array && !strings.HasSuffix(line[:end+1], "]]")) {
+		return "", false, fmt.Errorf("unterminated table header")
+	}
+	if rest := strings.TrimSpace(line[end+1:]); rest != "" {
+		return "", false, fmt.Errorf("unexpected text after table header: %s", rest)
+	}
+	header := line[1:end]
+	if array {
+		header = line[2 : end-1]
+	}
+	parts, err := parseTOMLKey(header)
+	if err != nil {
+		return "", false, err
+	}
+	return strings.Join(parts, "."), array, nil
+}
+
+// indexOutsideQuotes returns the index of the first c in s that is not within a quoted string, or -1
+func indexOutsideQuotes(s string, c byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++ // skip the escaped character
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote != 0:
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTOMLKey splits a key or a table name into its dotted parts, where each part is bare or quoted
+func parseTOMLKey(s string) ([]string, error) {
+	var parts []string
+	s = strings.TrimSpace(s)
+	for {
+		if s == "" {
+			return nil, fmt.Errorf("missing key")
+		}
+		var part string
+		if s[0] == '"' || s[0] == '\'' {
+			end := indexOutsideQuotes(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			value, err := parseTOMLValue(strings.TrimSpace(s[:end]))
+			if err != nil {
+				return nil, err
+			}
+			part, _ = value.(string)
+			s = s[end:]
+		} else {
+			end := strings.IndexByte(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			part = strings.TrimSpace(s[:end])
+			for _, r := range part {
+				if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+					return nil, fmt.Errorf("invalid key %q, keys with other characters than letters, digits, _ and - must be quoted", part)
+				}
+			}
+			if part == "" {
+				return nil, fmt.Errorf("empty key")
+			}
+			s = s[end:]
+		}
+		parts = append(parts, part)
+		if s = strings.TrimSpace(s); s == "" {
+			return parts, nil
+		}
+		// s now starts with "."
+		s = strings.TrimSpace(s[1:])
+	}
+}
+
+// parseTOMLValue parses a basic string, literal string, integer or boolean, followed by an optional comment
+func parseTOMLValue(s string) (any, error) {
+	if s == "" {
+		return nil, fmt.Errorf("missing value")
+	}
+	var (
+		value any
+		rest  string
+	)
+	switch s[0] {
+	case '"':
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		unquoted, err := unescapeTOML(s[1:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %v", s[:end+1], err)
+		}
+		value, rest = unquoted, s[end+1:]
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		value, rest = s[1:end+1], s[end+2:]
+	default:
+		word := s
+		if i := strings.IndexAny(s, " \t#"); i >= 0 {
+			word, rest = s[:i], s[i:]
+		}
+		switch word {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		default:
+			n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %s, strings must be quoted", word)
+			}
+			value = n
+		}
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return nil, fmt.Errorf("unexpected text after value: %s", rest)
+	}
+	return value, nil
+}
+
+// unescapeTOML replaces the escape sequences in the contents of a TOML basic string:
+// \b, \t, \n, \f, \r, \e, \", \\, \uXXXX and \UXXXXXXXX. Other escape sequences are errors.
+func unescapeTOML(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i++; i >= len(s) {
+			return "", fmt.Errorf("unterminated escape sequence")
+		}
+		switch s[i] {
+		case 'b':
+			sb.WriteByte('\b')
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'e':
+			sb.WriteByte(0x1b)
+		case '"', '\\':
+			sb.WriteByte(s[i])
+		case 'u', 'U':
+			digits := 4
+			if s[i] == 'U' {
+				digits = 8
+			}
+			if i+digits >= len(s) {
+				return "", fmt.Errorf("too short unicode escape sequence")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+digits], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", fmt.Errorf("invalid unicode escape sequence \\%s", s[i:i+1+digits])
+			}
+			sb.WriteRune(rune(r))
+			i += digits
+		default:
+			return "", fmt.Errorf("invalid escape sequence \\%c", s[i])
+		}
+	}
+	return sb.String(), nil
+}
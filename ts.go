@@ -17,13 +17,13 @@ var DefaultTabsSpaces = TabsSpaces{4, true}
 var languageIndentation = map[TabsSpaces][]Mode{
 	// Languages that use spaces (from the opinionated point of view of this package)
 	{1, true}: {ABC},
-	{2, true}: {Agda, Algol68, Amber, Arduino, Assembly, Blueprint, C3, Clojure, Config, CSS, CSound, Dart, Diff, Elixir, Erlang, Fortran90, FSTAB, Gleam, HTML, Haskell, Ignore, Ini, Inko, JSON, Koka, Lilypond, Lua, Nmap, Nix, ObjC, ObjectPascal, OCaml, Perl, PolicyLanguage, POV, ReStructured, Ruby, Scala, Scheme, Shell, StandardML, Teal, Vim, XML},
+	{2, true}: {Agda, Algol68, Amber, Arduino, Assembly, Blueprint, C3, Clojure, CodeOwners, Config, CSS, CSound, Dart, Diff, Elixir, ERB, Erlang, Fortran90, FSTAB, Gleam, HTML, Haskell, Ignore, Ini, Inko, JSON, Koka, Lilypond, Lua, Nmap, Nix, ObjC, ObjectPascal, OCaml, Perl, PolicyLanguage, POV, ReStructured, Ruby, Scala, Scheme, Shell, StandardML, Teal, Vim, XML},
 	{3, true}: {Ada, Prolog}, // Ada and Prolog are special
-	{4, true}: {ASCIIDoc, Basic, Bat, Battlestar, Beef, CMake, Chuck, CS, Cpp, COBOL, Crystal, Docker, Elm, Email, Faust, FSharp, GDScript, Garnet, Git, Haxe, JSON, Jakt, Java, JavaScript, Kotlin, Markdown, Mojo, Nim, Oak, Ollama, PHP, Python, R, Rust, SCDoc, Skill, Spec, SQL, Starlark, Subversion, Swift, Terra, Text, Tim, TypeScript, V, Zig},
+	{4, true}: {ASCIIDoc, Basic, Bat, Battlestar, Beef, CMake, Chuck, CS, Cpp, COBOL, Crystal, Docker, Elm, Email, Faust, FSharp, GDScript, Garnet, Git, Groovy, Haxe, JSON, Jakt, Java, JavaScript, Julia, Kotlin, Markdown, Mojo, Nim, Oak, Ollama, PHP, Procfile, Python, R, Rust, SCDoc, Skill, Spec, SQL, Starlark, Subversion, Swift, Terra, Text, Tim, TypeScript, V, Zig},
 	{7, true}: {Fortran77},        // Fortran77 is weird
 	{8, true}: {GoMod, Hare, Ivy}, // go.mod files, Hare and Ivy are special
 	// Languages that use tabs (from the opinionated point of view of this package)
-	{4, false}: {AIDL, C, Dingo, Go, GoAssembly, HIDL, Just, Lisp, M4, Make, ManPage, Nroff, Odin, Shader, SuperCollider}, // Tabs
+	{4, false}: {AIDL, C, Caddyfile, Dingo, Go, GoAssembly, HIDL, Just, Lisp, M4, Make, ManPage, Nroff, Odin, Shader, SuperCollider}, // Tabs
 }
 
 // Spaces returns true if spaces should be used for the current mode